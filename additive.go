@@ -0,0 +1,168 @@
+package audio
+
+import (
+	"math"
+	"math/cmplx"
+)
+
+// Partial is a sine component of a spectrum, at Ratio times the fundamental frequency.
+type Partial struct {
+	Ratio, Amplitude float64
+}
+
+// AdditiveBank efficiently sums many sine partials using the complex rotation technique of SineOsc.
+// The frequency and amplitude of each partial may be changed immediately or ramped linearly over time.
+// Partials approaching the Nyquist frequency are faded out to avoid aliasing.
+type AdditiveBank struct {
+	pidt     float64
+	srate    float64
+	freq     float64
+	partials []additivePartial
+	n        int
+}
+
+type additivePartial struct {
+	ratio  float64
+	freq   float64 // target frequency
+	x, d   complex128
+	dEnd   complex128 // rotation at the end of a frequency ramp
+	r      complex128 // per-sample change in rotation during a frequency ramp
+	fn     int        // samples left in the frequency ramp
+	amp    float64
+	ampEnd float64
+	da     float64
+	an     int // samples left in the amplitude ramp
+	gain   float64
+}
+
+func NewAdditiveBank(partials []Partial) *AdditiveBank {
+	b := &AdditiveBank{}
+	b.SetSpectrum(partials)
+	return b
+}
+
+func (b *AdditiveBank) InitAudio(p Params) {
+	b.pidt = math.Pi / p.SampleRate
+	b.srate = p.SampleRate
+	for i := range b.partials {
+		b.setFreq(i, b.partials[i].ratio*b.freq, 0)
+	}
+}
+
+// SetSpectrum replaces the partials.  Their phases are reset to zero.
+func (b *AdditiveBank) SetSpectrum(partials []Partial) {
+	b.partials = make([]additivePartial, len(partials))
+	for i, p := range partials {
+		q := &b.partials[i]
+		q.ratio = p.Ratio
+		q.amp = p.Amplitude
+		q.ampEnd = p.Amplitude
+		q.x = 1
+		b.setFreq(i, p.Ratio*b.freq, 0)
+	}
+}
+
+// Len returns the number of partials.
+func (b *AdditiveBank) Len() int { return len(b.partials) }
+
+// Freq sets the fundamental frequency immediately.
+func (b *AdditiveBank) Freq(freq float64) *AdditiveBank {
+	return b.Glide(freq, 0)
+}
+
+// Glide ramps the fundamental frequency to freq over t seconds.
+func (b *AdditiveBank) Glide(freq, t float64) *AdditiveBank {
+	b.freq = freq
+	for i := range b.partials {
+		b.setFreq(i, b.partials[i].ratio*freq, t)
+	}
+	return b
+}
+
+// PartialRatio ramps the ratio of partial i to the fundamental to ratio over t seconds.
+// To give a partial a fixed frequency independent of the others, set the fundamental to 1 and the ratio to the frequency.
+func (b *AdditiveBank) PartialRatio(i int, ratio, t float64) {
+	b.partials[i].ratio = ratio
+	b.setFreq(i, ratio*b.freq, t)
+}
+
+// PartialAmplitude ramps the amplitude of partial i to amp over t seconds.
+func (b *AdditiveBank) PartialAmplitude(i int, amp, t float64) {
+	q := &b.partials[i]
+	q.ampEnd = amp
+	q.an = int(t * b.srate)
+	if q.an <= 0 {
+		q.amp = amp
+		q.an = 0
+		return
+	}
+	q.da = (amp - q.amp) / float64(q.an)
+}
+
+// PartialPhase sets the phase (0..1) of partial i.
+func (b *AdditiveBank) PartialPhase(i int, phase float64) {
+	b.partials[i].x = cmplx.Exp(complex(0, 2*math.Pi*phase))
+}
+
+func (b *AdditiveBank) setFreq(i int, freq, t float64) {
+	q := &b.partials[i]
+	prev := q.freq
+	q.freq = freq
+	if b.srate == 0 {
+		return // not yet initialized
+	}
+
+	// During a ramp, the higher of the two frequencies determines the gain.
+	q.gain = b.bandGain(freq)
+	if t > 0 {
+		q.gain = math.Min(q.gain, b.bandGain(prev))
+	}
+
+	q.dEnd = expwdt(freq * b.pidt)
+	q.fn = int(t * b.srate)
+	if q.fn <= 0 {
+		q.d = q.dEnd
+		q.fn = 0
+		return
+	}
+	// Rotate the rotation a little each sample so that the frequency ramps linearly.
+	q.r = cmplx.Pow(q.dEnd/q.d, complex(1/float64(q.fn), 0))
+}
+
+// bandGain fades out partials in the top 5% of the band.
+func (b *AdditiveBank) bandGain(freq float64) float64 {
+	nyquist := b.srate / 2
+	return math.Max(0, math.Min(1, (nyquist-math.Abs(freq))/(.05*nyquist)))
+}
+
+func (b *AdditiveBank) Sing() float64 {
+	y := 0.0
+	for i := range b.partials {
+		q := &b.partials[i]
+		if q.fn > 0 {
+			q.fn--
+			q.d *= q.r
+			if q.fn == 0 {
+				q.d = q.dEnd
+				q.gain = b.bandGain(q.freq)
+			}
+		}
+		if q.an > 0 {
+			q.an--
+			q.amp += q.da
+			if q.an == 0 {
+				q.amp = q.ampEnd
+			}
+		}
+		q.x *= q.d
+		y += q.gain * q.amp * imag(q.x)
+	}
+	b.n++
+	if b.n == renormPeriod {
+		b.n = 0
+		for i := range b.partials {
+			b.partials[i].x = renormalize(b.partials[i].x)
+		}
+	}
+	return y
+}
@@ -0,0 +1,71 @@
+package audio
+
+import (
+	"math"
+	"math/cmplx"
+	"testing"
+)
+
+func TestAdditiveBank(t *testing.T) {
+	const sr = 48000
+	partials := []Partial{{1, 1}, {9, .5}, {22, .25}}
+	b := NewAdditiveBank(partials)
+	Init(b, Params{sr})
+	b.Freq(440)
+	maxErr := 0.0
+	for n := 1; n <= sr/10; n++ {
+		want := 0.0
+		for _, p := range partials {
+			want += p.Amplitude * math.Sin(2*math.Pi*440*p.Ratio*float64(n)/sr)
+		}
+		maxErr = math.Max(maxErr, math.Abs(b.Sing()-want))
+	}
+	if maxErr > 1e-3 {
+		t.Errorf("max difference from reference = %v", maxErr)
+	}
+}
+
+func TestAdditiveBankRamps(t *testing.T) {
+	const sr = 48000
+	b := NewAdditiveBank([]Partial{{1, 0}, {3, 1}})
+	Init(b, Params{sr})
+	b.Freq(100)
+	b.Glide(200, .01)
+	b.PartialAmplitude(0, 1, .01)
+	for n := 1; n <= sr/100; n++ {
+		b.Sing()
+		u := float64(n) / (sr / 100)
+		for i, ratio := range []float64{1, 3} {
+			freq := cmplx.Phase(b.partials[i].d) * sr / (2 * math.Pi)
+			if want := ratio * (100 + 100*u); math.Abs(freq-want) > 1e-3*want {
+				t.Fatalf("sample %d: partial %d frequency = %v, want %v", n, i, freq, want)
+			}
+		}
+		if amp := b.partials[0].amp; math.Abs(amp-u) > 1e-9 {
+			t.Fatalf("sample %d: amplitude = %v, want %v", n, amp, u)
+		}
+	}
+}
+
+func TestAdditiveBankNyquist(t *testing.T) {
+	const sr = 48000
+	b := NewAdditiveBank([]Partial{{1, 1}})
+	Init(b, Params{sr})
+	// Partials fade out over the top 5% of the band, from 22800 Hz.
+	for _, c := range []struct{ freq, gain float64 }{
+		{10000, 1},
+		{22800, 1},
+		{23400, .5},
+		{24000, 0},
+		{30000, 0},
+	} {
+		b.Freq(c.freq)
+		peak := 0.0
+		for n := 0; n < 4800; n++ {
+			peak = math.Max(peak, math.Abs(b.Sing()))
+		}
+		if math.Abs(peak-c.gain) > .01 {
+			t.Errorf("%v Hz: peak %v, want %v", c.freq, peak, c.gain)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package audio
+
+import "math"
+
+// A Curve shapes an envelope stage.  Zero is linear.  Positive values move quickly at first and slowly at
+// the end, like an exponential approach to the target; negative values move slowly at first and quickly at the
+// end (logarithmic).  The magnitude sets the strength of the curvature.
+type Curve float64
+
+const (
+	LinearCurve Curve = 0
+	ExpCurve    Curve = 4.6 // like the exponential approach of ExpEnv.Go, which gets within 1% of its target
+	LogCurve    Curve = -4.6
+)
+
+// shape maps the progress x (0..1) through a stage to the fraction of the change in level.
+func (c Curve) shape(x float64) float64 {
+	if math.Abs(float64(c)) < 1e-6 {
+		return x
+	}
+	return math.Expm1(-float64(c)*x) / math.Expm1(-float64(c))
+}
+
+// ADSR is an attack-decay-sustain-release envelope driven by a gate.  While the gate is on, the envelope
+// rises to its peak in Attack seconds, falls to the Sustain level (relative to the peak) in Decay seconds and
+// stays there; when the gate goes off, it falls to zero in Release seconds.  The peak is 1, scaled by the
+// velocity according to VelocitySensitivity.
+type ADSR struct {
+	Attack, Decay, Release float64
+	Sustain                float64
+	// AttackCurve, DecayCurve and ReleaseCurve shape each stage.
+	AttackCurve, DecayCurve, ReleaseCurve Curve
+	// If Legato is true, turning the gate on while it is already on (e.g., for overlapping notes) continues
+	// the envelope.  Otherwise, each gate on retriggers the attack, starting from the current level.
+	Legato bool
+	// VelocitySensitivity (0..1) is how much the velocity affects the peak:  0 ignores it; 1 scales the peak
+	// by it.
+	VelocitySensitivity float64
+
+	dt       float64
+	stage    adsrStage
+	gate     bool
+	started  bool // whether the gate has been turned on
+	velocity float64
+	peak     float64
+	from, to float64
+	i, n     int // progress through the stage, in samples
+	y        float64
+}
+
+type adsrStage int
+
+const (
+	adsrIdle adsrStage = iota
+	adsrAttack
+	adsrDecay
+	adsrSustain
+	adsrRelease
+)
+
+func NewADSR(attack, decay, sustain, release float64) *ADSR {
+	return &ADSR{Attack: attack, Decay: decay, Sustain: sustain, Release: release, velocity: 1}
+}
+
+func (e *ADSR) InitAudio(p Params) {
+	e.dt = 1 / p.SampleRate
+}
+
+// Velocity sets the velocity (0..1) applied by the next gate on.
+func (e *ADSR) Velocity(v float64) *ADSR {
+	e.velocity = v
+	return e
+}
+
+// Gate turns the gate on (starting the attack) or off (starting the release).
+func (e *ADSR) Gate(on bool) {
+	if on {
+		if e.gate && e.Legato {
+			return
+		}
+		e.started = true
+		e.peak = 1 - e.VelocitySensitivity + e.VelocitySensitivity*e.velocity
+		e.start(adsrAttack)
+	} else if e.gate {
+		e.start(adsrRelease)
+	}
+	e.gate = on
+}
+
+// start begins a stage from the current level.
+func (e *ADSR) start(s adsrStage) {
+	e.stage = s
+	e.from = e.y
+	e.i = 0
+	var t float64
+	switch s {
+	case adsrAttack:
+		e.to, t = e.peak, e.Attack
+	case adsrDecay:
+		e.to, t = e.Sustain*e.peak, e.Decay
+	case adsrRelease:
+		e.to, t = 0, e.Release
+	default:
+		return
+	}
+	e.n = int(math.Max(1, math.Floor(t/e.dt+.5)))
+}
+
+func (e *ADSR) curve() Curve {
+	switch e.stage {
+	case adsrAttack:
+		return e.AttackCurve
+	case adsrDecay:
+		return e.DecayCurve
+	}
+	return e.ReleaseCurve
+}
+
+func (e *ADSR) Sing() float64 {
+	switch e.stage {
+	case adsrAttack, adsrDecay, adsrRelease:
+		e.i++
+		if e.i < e.n {
+			e.y = e.from + (e.to-e.from)*e.curve().shape(float64(e.i)/float64(e.n))
+			break
+		}
+		e.y = e.to
+		switch e.stage {
+		case adsrAttack:
+			e.start(adsrDecay)
+		case adsrDecay:
+			e.stage = adsrSustain
+		case adsrRelease:
+			e.stage = adsrIdle
+		}
+	case adsrSustain:
+		e.y = e.Sustain * e.peak
+	}
+	return e.y
+}
+
+// Done reports whether the envelope has finished its release.  It is false until the gate is first turned on,
+// so that a voice added to a MultiVoice before its note starts isn't discarded.
+func (e *ADSR) Done() bool {
+	return e.started && e.stage == adsrIdle
+}
@@ -0,0 +1,85 @@
+package audio
+
+import (
+	"math"
+	"testing"
+)
+
+func TestADSR(t *testing.T) {
+	const sampleRate = 1000
+	e := NewADSR(.1, .2, .5, .3)
+	Init(e, Params{sampleRate})
+	run := func(n int) float64 {
+		y := 0.0
+		for i := 0; i < n; i++ {
+			y = e.Sing()
+		}
+		return y
+	}
+	// Not done before the first gate, e.g. while waiting in a MultiVoice for its note.
+	m := &MultiVoice{Params: Params{sampleRate}}
+	m.Add(e)
+	for i := 0; i < 10; i++ {
+		m.Sing()
+	}
+	if e.Done() || len(m.Voices) != 1 {
+		t.Fatal("expected the envelope not to be done before the gate is turned on")
+	}
+
+	e.Gate(true)
+	if y := run(50); math.Abs(y-.5) > .01 {
+		t.Errorf("expected .5 halfway through a linear attack, got %f", y)
+	}
+	if y := run(50); math.Abs(y-1) > 1e-9 {
+		t.Errorf("expected the peak at the end of the attack, got %f", y)
+	}
+	if y := run(300); math.Abs(y-.5) > 1e-9 {
+		t.Errorf("expected the sustain level, got %f", y)
+	}
+	e.Gate(false)
+	if y := run(150); math.Abs(y-.25) > .01 {
+		t.Errorf("expected .25 halfway through the release, got %f", y)
+	}
+	run(150)
+	if !e.Done() {
+		t.Error("expected the envelope to be done after the release")
+	}
+
+	// curves
+	for _, c := range []struct {
+		curve Curve
+		mid   float64
+	}{{ExpCurve, .9}, {LogCurve, .1}} {
+		e := NewADSR(.1, 0, 1, 0)
+		e.AttackCurve = c.curve
+		Init(e, Params{sampleRate})
+		e.Gate(true)
+		y := 0.0
+		for i := 0; i < 50; i++ {
+			y = e.Sing()
+		}
+		if math.Abs(y-c.mid) > .01 {
+			t.Errorf("curve %v: expected %f halfway through the attack, got %f", c.curve, c.mid, y)
+		}
+	}
+
+	// legato, retrigger and velocity
+	for _, legato := range []bool{false, true} {
+		e := NewADSR(.1, .1, .5, .1)
+		e.Legato = legato
+		e.VelocitySensitivity = 1
+		Init(e, Params{sampleRate})
+		e.Velocity(.8).Gate(true)
+		for i := 0; i < 300; i++ {
+			e.Sing()
+		}
+		e.Gate(true)
+		y := e.Sing()
+		if legato && math.Abs(y-.4) > 1e-9 {
+			t.Errorf("legato: expected to continue sustaining at .4, got %f", y)
+		}
+		if !legato && y <= .4 {
+			t.Errorf("retrigger: expected a new attack from .4, got %f", y)
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package audio
+
+import "math"
+
+// BFormat is a sample of a first-order Ambisonic sound field:  the channels W, X, Y and Z in the traditional
+// (Furse-Malham) convention, where W carries the omnidirectional signal scaled by 1/√2, X points forward,
+// Y points left and Z points up.
+type BFormat [4]float64
+
+type BFormatVoice interface {
+	Sing() BFormat
+	Done() bool
+}
+
+// Direction is a direction from the listener, in radians.  Azimuth 0 is straight ahead and increases to the
+// right; elevation 0 is level and increases upwards.
+type Direction struct {
+	Azimuth, Elevation float64
+}
+
+// axes returns the unit vector in the B-format axes (forward, left, up).
+func (d Direction) axes() (x, y, z float64) {
+	c := math.Cos(d.Elevation)
+	return math.Cos(d.Azimuth) * c, -math.Sin(d.Azimuth) * c, math.Sin(d.Elevation)
+}
+
+// EncodeBFormat places the mono sample x in direction d.
+func EncodeBFormat(x float64, d Direction) BFormat {
+	dx, dy, dz := d.axes()
+	return BFormat{x / math.Sqrt2, x * dx, x * dy, x * dz}
+}
+
+func (b BFormat) Add(c BFormat) BFormat {
+	return BFormat{b[0] + c[0], b[1] + c[1], b[2] + c[2], b[3] + c[3]}
+}
+
+// Rotate rotates the sound field by roll (right side down), then pitch (front up), then yaw (to the right),
+// all in radians.
+func (b BFormat) Rotate(yaw, pitch, roll float64) BFormat {
+	x, y, z := b[1], b[2], b[3]
+	s, c := math.Sincos(roll)
+	y, z = y*c-z*s, y*s+z*c
+	s, c = math.Sincos(pitch)
+	x, z = x*c-z*s, x*s+z*c
+	s, c = math.Sincos(yaw)
+	x, y = x*c+y*s, y*c-x*s
+	return BFormat{b[0], x, y, z}
+}
+
+// AmbiEncoder encodes a mono Voice in the direction given by its Azimuth and Elevation Controls.
+type AmbiEncoder struct {
+	Voice              Voice
+	Azimuth, Elevation Control
+}
+
+func NewAmbiEncoder(v Voice) *AmbiEncoder {
+	return &AmbiEncoder{Voice: v}
+}
+
+func (e *AmbiEncoder) Sing() BFormat {
+	return EncodeBFormat(e.Voice.Sing(), Direction{e.Azimuth.Sing(), e.Elevation.Sing()})
+}
+
+func (e *AmbiEncoder) Done() bool {
+	return e.Voice.Done()
+}
+
+// AmbiMix is the BFormat analog of MultiVoice.
+type AmbiMix struct {
+	Params Params
+	Voices []BFormatVoice
+}
+
+func (m *AmbiMix) Add(v BFormatVoice) {
+	Init(v, m.Params)
+	m.Voices = append(m.Voices, v)
+}
+
+func (m *AmbiMix) Sing() BFormat {
+	b := BFormat{}
+	for i, n := 0, len(m.Voices); i < n; {
+		v := m.Voices[i]
+		b = b.Add(v.Sing())
+		if v.Done() {
+			n--
+			m.Voices[i] = m.Voices[n]
+			m.Voices[n] = nil
+			m.Voices = m.Voices[:n]
+		} else {
+			i++
+		}
+	}
+	return b
+}
+
+func (m *AmbiMix) Done() bool {
+	return len(m.Voices) == 0
+}
+
+// AmbiRotator rotates a sound field by its Yaw, Pitch and Roll Controls (see BFormat.Rotate).
+type AmbiRotator struct {
+	Source           BFormatVoice
+	Yaw, Pitch, Roll Control
+}
+
+func NewAmbiRotator(src BFormatVoice) *AmbiRotator {
+	return &AmbiRotator{Source: src}
+}
+
+func (r *AmbiRotator) Sing() BFormat {
+	return r.Source.Sing().Rotate(r.Yaw.Sing(), r.Pitch.Sing(), r.Roll.Sing())
+}
+
+func (r *AmbiRotator) Done() bool {
+	return r.Source.Done()
+}
+
+// AmbiDecoder decodes a sound field to a number of output channels, each a weighted sum of W, X, Y and Z.
+type AmbiDecoder struct {
+	Source BFormatVoice
+	gains  []BFormat
+	out    []float64
+}
+
+// NewAmbiDecoder returns a decoder for a regular layout of speakers, i.e., one whose speakers are spread
+// evenly around a circle (if they all have zero elevation) or over a sphere.
+func NewAmbiDecoder(src BFormatVoice, speakers ...Direction) *AmbiDecoder {
+	horizontal := true
+	for _, s := range speakers {
+		if s.Elevation != 0 {
+			horizontal = false
+		}
+	}
+	dims := 3.0
+	if horizontal {
+		dims = 2
+	}
+	n := float64(len(speakers))
+	d := &AmbiDecoder{Source: src}
+	for _, s := range speakers {
+		x, y, z := s.axes()
+		if horizontal {
+			z = 0
+		}
+		d.gains = append(d.gains, BFormat{math.Sqrt2 / n, dims * x / n, dims * y / n, dims * z / n})
+	}
+	return d
+}
+
+// NewAmbiStereoDecoder returns a decoder for a pair of coincident virtual microphones pointing angle radians
+// to the left and right.  directivity ranges from 0 (omnidirectional) to 1 (figure-of-eight); .5 is cardioid.
+func NewAmbiStereoDecoder(src BFormatVoice, angle, directivity float64) *AmbiDecoder {
+	d := &AmbiDecoder{Source: src}
+	for _, a := range []float64{-angle, angle} {
+		x, y, z := Direction{Azimuth: a}.axes()
+		k := directivity
+		d.gains = append(d.gains, BFormat{(1 - k) * math.Sqrt2, k * x, k * y, k * z})
+	}
+	return d
+}
+
+// NumChannels returns the number of output channels.
+func (d *AmbiDecoder) NumChannels() int {
+	return len(d.gains)
+}
+
+// SingChannels renders one sample for each output channel into out, which must have length d.NumChannels().
+func (d *AmbiDecoder) SingChannels(out []float64) {
+	d.decode(d.Source.Sing(), out)
+}
+
+func (d *AmbiDecoder) decode(b BFormat, out []float64) {
+	for i, g := range d.gains {
+		out[i] = g[0]*b[0] + g[1]*b[1] + g[2]*b[2] + g[3]*b[3]
+	}
+}
+
+// Sing renders the first two channels, making a stereo decoder a StereoVoice.
+func (d *AmbiDecoder) Sing() (float64, float64) {
+	if len(d.out) != len(d.gains) {
+		d.out = make([]float64, len(d.gains))
+	}
+	d.SingChannels(d.out)
+	switch len(d.out) {
+	case 0:
+		return 0, 0
+	case 1:
+		return d.out[0], d.out[0]
+	}
+	return d.out[0], d.out[1]
+}
+
+func (d *AmbiDecoder) Done() bool {
+	return d.Source.Done()
+}
+
+// AmbiBinauralDecoder decodes a sound field for headphones by rendering a cube of virtual speakers binaurally.
+type AmbiBinauralDecoder struct {
+	Decoder   *AmbiDecoder
+	Binaurals []*Binaural
+	speakers  []float64
+}
+
+func NewAmbiBinauralDecoder(src BFormatVoice) *AmbiBinauralDecoder {
+	e := math.Atan(1 / math.Sqrt2)
+	var speakers []Direction
+	for _, el := range []float64{e, -e} {
+		for _, az := range []float64{45, 135, -135, -45} {
+			speakers = append(speakers, Direction{az * math.Pi / 180, el})
+		}
+	}
+	d := &AmbiBinauralDecoder{Decoder: NewAmbiDecoder(src, speakers...), speakers: make([]float64, len(speakers))}
+	for _, s := range speakers {
+		d.Binaurals = append(d.Binaurals, NewBinaural().Direction(s.Azimuth, s.Elevation))
+	}
+	return d
+}
+
+func (d *AmbiBinauralDecoder) Sing() (float64, float64) {
+	d.Decoder.SingChannels(d.speakers)
+	l, r := 0.0, 0.0
+	for i, b := range d.Binaurals {
+		bl, br := b.Process(d.speakers[i])
+		l += bl
+		r += br
+	}
+	return l, r
+}
+
+func (d *AmbiBinauralDecoder) Done() bool {
+	return d.Decoder.Done()
+}
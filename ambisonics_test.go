@@ -0,0 +1,120 @@
+package audio
+
+import (
+	"math"
+	"testing"
+)
+
+func TestAmbiDecoder(t *testing.T) {
+	speakers := []Direction{{0, 0}, {math.Pi / 2, 0}, {math.Pi, 0}, {-math.Pi / 2, 0}}
+	d := NewAmbiDecoder(nil, speakers...)
+	out := make([]float64, len(speakers))
+	for i, s := range speakers {
+		d.decode(EncodeBFormat(1, s), out)
+		for j := range out {
+			if j != i && out[j] >= out[i] {
+				t.Errorf("source at speaker %d: speaker %d got %.2f >= %.2f", i, j, out[j], out[i])
+			}
+		}
+
+		// rotating a source ahead should bring it to the same speaker
+		d.decode(EncodeBFormat(1, Direction{}).Rotate(s.Azimuth, 0, 0), out)
+		for j := range out {
+			if j != i && out[j] >= out[i] {
+				t.Errorf("source rotated to speaker %d: speaker %d got %.2f >= %.2f", i, j, out[j], out[i])
+			}
+		}
+	}
+
+	sum := 0.0
+	d.decode(EncodeBFormat(1, Direction{.3, 0}), out)
+	for _, x := range out {
+		sum += x
+	}
+	if math.Abs(sum-1) > 1e-9 {
+		t.Errorf("expected total gain 1, got %f", sum)
+	}
+}
+
+func TestBFormatRotate(t *testing.T) {
+	near := func(a, b BFormat) bool {
+		for i := range a {
+			if math.Abs(a[i]-b[i]) > 1e-9 {
+				return false
+			}
+		}
+		return true
+	}
+	energy := func(b BFormat) float64 { return b[1]*b[1] + b[2]*b[2] + b[3]*b[3] }
+
+	// Yaw turns the field to the right, pitch tilts the front up.
+	if b, want := EncodeBFormat(1, Direction{.3, .2}).Rotate(.5, 0, 0), EncodeBFormat(1, Direction{.8, .2}); !near(b, want) {
+		t.Errorf("yaw:  got %v, want %v", b, want)
+	}
+	if b, want := EncodeBFormat(1, Direction{}).Rotate(0, .4, 0), EncodeBFormat(1, Direction{0, .4}); !near(b, want) {
+		t.Errorf("pitch:  got %v, want %v", b, want)
+	}
+	if b, want := EncodeBFormat(1, Direction{math.Pi / 2, 0}).Rotate(0, 0, .4), EncodeBFormat(1, Direction{math.Pi / 2, -.4}); !near(b, want) {
+		t.Errorf("roll:  got %v, want %v", b, want)
+	}
+
+	// Any rotation keeps the omnidirectional and directional energy.
+	b := EncodeBFormat(.7, Direction{1, -.3})
+	r := b.Rotate(2, -1, .5)
+	if r[0] != b[0] || math.Abs(energy(r)-energy(b)) > 1e-12 {
+		t.Errorf("rotation changed the field's energy:  %v -> %v", b, r)
+	}
+}
+
+func TestAmbiStereoDecoder(t *testing.T) {
+	// Cardioids pointing left and right.
+	d := NewAmbiStereoDecoder(nil, math.Pi/2, .5)
+	out := make([]float64, 2)
+	for _, c := range []struct {
+		azimuth float64
+		l, r    float64
+	}{
+		{-math.Pi / 2, 1, 0},
+		{0, .5, .5},
+		{math.Pi / 2, 0, 1},
+		{math.Pi, .5, .5},
+	} {
+		d.decode(EncodeBFormat(1, Direction{c.azimuth, 0}), out)
+		if math.Abs(out[0]-c.l) > 1e-9 || math.Abs(out[1]-c.r) > 1e-9 {
+			t.Errorf("azimuth %.2f:  got %.3f, %.3f, want %.3f, %.3f", c.azimuth, out[0], out[1], c.l, c.r)
+		}
+	}
+}
+
+func TestAmbiBinauralDecoder(t *testing.T) {
+	const sampleRate = 48000
+	// energy returns the energy in each ear of noise encoded at azimuth, rotated by yaw and decoded binaurally.
+	energy := func(azimuth, yaw float64) (l, r float64) {
+		e := NewAmbiEncoder(NewWhiteNoise(1))
+		e.Azimuth.SetPoints([]*ControlPoint{{Time: 0, Value: azimuth}})
+		rot := NewAmbiRotator(e)
+		rot.Yaw.SetPoints([]*ControlPoint{{Time: 0, Value: yaw}})
+		mix := &AmbiMix{Params: Params{sampleRate}}
+		mix.Add(rot)
+		d := NewAmbiBinauralDecoder(mix)
+		Init(d, Params{sampleRate})
+		for i := 0; i < sampleRate/2; i++ {
+			x, y := d.Sing()
+			if i >= sampleRate/10 {
+				l += x * x
+				r += y * y
+			}
+		}
+		return
+	}
+
+	if l, r := energy(math.Pi/2, 0); r < 2*l {
+		t.Errorf("source at the right:  expected the right ear to be louder, got energies %g (left) and %g (right)", l, r)
+	}
+	if l, r := energy(0, -math.Pi/2); l < 2*r {
+		t.Errorf("source rotated to the left:  expected the left ear to be louder, got energies %g (left) and %g (right)", l, r)
+	}
+	if l, r := energy(0, 0); math.Abs(l-r) > 1e-6*(l+r) {
+		t.Errorf("source ahead:  expected equal energies, got %g (left) and %g (right)", l, r)
+	}
+}
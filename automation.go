@@ -0,0 +1,163 @@
+package audio
+
+import (
+	"math"
+	"sync"
+	"sync/atomic"
+)
+
+// An AutomationRecorder records the movements of a parameter, e.g. from a UI or MIDI controller, as
+// ControlPoints.  Set may be called from any goroutine; the recorder samples the latest value once per
+// sample as it is sung, so that the recorded times follow the audio clock.  Its output is the value, so it can
+// drive the parameter live while recording.
+//
+// The recorded points (see Points) can be played back like any other, e.g. as a Pattern attribute played by
+// a PatternPlayer.
+type AutomationRecorder struct {
+	// These are accessed atomically, and are first for 64-bit alignment.
+	value uint64 // the bits of the latest value
+	k     int64  // the current time, in samples
+
+	// Tolerance is the largest difference in value allowed when thinning the recorded points.
+	Tolerance float64
+
+	params Params
+
+	mu      sync.Mutex
+	x       float64 // the current value; changed only by Sing, under mu
+	points  []*ControlPoint
+	started bool
+	lastK   int64 // the time of the last point, in samples
+}
+
+// NewAutomationRecorder returns a recorder starting at value x.
+func NewAutomationRecorder(x, tolerance float64) *AutomationRecorder {
+	r := &AutomationRecorder{Tolerance: tolerance, x: x}
+	r.Set(x)
+	return r
+}
+
+func (r *AutomationRecorder) InitAudio(p Params) {
+	t := r.GetTime() // handle sample rate change
+	r.params = p
+	r.SetTime(t)
+}
+
+// Set sets the value.  It is safe to call from any goroutine.
+func (r *AutomationRecorder) Set(x float64) {
+	atomic.StoreUint64(&r.value, math.Float64bits(x))
+}
+
+func (r *AutomationRecorder) GetTime() float64 {
+	if r.params.SampleRate == 0 {
+		return 0
+	}
+	return float64(atomic.LoadInt64(&r.k)) / r.params.SampleRate
+}
+
+// SetTime moves the recorder to time t, e.g. to follow the PatternPlayer whose part is being recorded.
+// Recording continues from t, replacing any points already recorded after it.
+func (r *AutomationRecorder) SetTime(t float64) {
+	atomic.StoreInt64(&r.k, int64(t*r.params.SampleRate))
+	r.mu.Lock()
+	i := len(r.points)
+	for i > 0 && r.points[i-1].Time >= t {
+		i--
+	}
+	r.points = r.points[:i]
+	r.started = false
+	r.mu.Unlock()
+}
+
+func (r *AutomationRecorder) Sing() float64 {
+	k := atomic.AddInt64(&r.k, 1) // like Control, each sample is at the time after the previous one
+	x := math.Float64frombits(atomic.LoadUint64(&r.value))
+	if !r.started || x != r.x {
+		r.mu.Lock()
+		switch {
+		case !r.started:
+			// Start the take at the time it was set to.
+			r.add(k-1, x)
+			r.started = true
+		case r.lastK < k-1:
+			// End the hold at the previous value, so that the change is a jump rather than a ramp.
+			r.add(k-1, r.x)
+			fallthrough
+		default:
+			r.add(k, x)
+		}
+		r.x = x
+		r.mu.Unlock()
+	}
+	return r.x
+}
+
+func (r *AutomationRecorder) add(k int64, x float64) {
+	r.points = append(r.points, &ControlPoint{Time: float64(k) / r.params.SampleRate, Value: x})
+	r.lastK = k
+}
+
+func (r *AutomationRecorder) Done() bool { return false }
+
+// Points returns the points recorded so far, thinned to within Tolerance, ending at the current time.  It is
+// safe to call from any goroutine.
+func (r *AutomationRecorder) Points() []*ControlPoint {
+	r.mu.Lock()
+	k := atomic.LoadInt64(&r.k)
+	points := make([]*ControlPoint, len(r.points), len(r.points)+1)
+	for i, p := range r.points {
+		q := *p
+		points[i] = &q
+	}
+	if r.started && r.lastK < k {
+		points = append(points, &ControlPoint{Time: float64(k) / r.params.SampleRate, Value: r.x})
+	}
+	r.mu.Unlock()
+	return SimplifyControlPoints(points, r.Tolerance)
+}
+
+// Clear discards the recorded points.
+func (r *AutomationRecorder) Clear() {
+	r.mu.Lock()
+	r.points = nil
+	r.started = false
+	r.mu.Unlock()
+}
+
+// SimplifyControlPoints returns a subset of points which, interpolated linearly, differs from points by at
+// most tolerance (using the Ramer-Douglas-Peucker algorithm).  The first and last points are always kept.
+func SimplifyControlPoints(points []*ControlPoint, tolerance float64) []*ControlPoint {
+	if len(points) < 3 {
+		return points
+	}
+	keep := make([]bool, len(points))
+	keep[0], keep[len(points)-1] = true, true
+	simplifyControlPoints(points, keep, tolerance)
+	var s []*ControlPoint
+	for i, p := range points {
+		if keep[i] {
+			s = append(s, p)
+		}
+	}
+	return s
+}
+
+func simplifyControlPoints(points []*ControlPoint, keep []bool, tolerance float64) {
+	a, b := points[0], points[len(points)-1]
+	maxErr, j := 0.0, 0
+	for i, p := range points[1 : len(points)-1] {
+		x := a.Value
+		if b.Time > a.Time {
+			x += (b.Value - a.Value) * (p.Time - a.Time) / (b.Time - a.Time)
+		}
+		if err := math.Abs(p.Value - x); err > maxErr {
+			maxErr, j = err, i+1
+		}
+	}
+	if maxErr <= tolerance {
+		return
+	}
+	keep[j] = true
+	simplifyControlPoints(points[:j+1], keep[:j+1], tolerance)
+	simplifyControlPoints(points[j:], keep[j:], tolerance)
+}
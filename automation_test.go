@@ -0,0 +1,120 @@
+package audio
+
+import (
+	"math"
+	"sort"
+	"testing"
+)
+
+func TestAutomationRecorder(t *testing.T) {
+	const sr = 1000
+	const tolerance = .001
+	r := NewAutomationRecorder(0, tolerance)
+	Init(r, Params{sr})
+
+	done := make(chan bool)
+	var y []float64
+	for i := 0; i < 2*sr; i++ {
+		go func() {
+			switch {
+			case i < sr:
+				r.Set(math.Sin(math.Pi / 2 * float64(i) / sr))
+			case i == 3*sr/2:
+				r.Set(2)
+			}
+			done <- true
+		}()
+		<-done
+		y = append(y, r.Sing())
+	}
+
+	// The recording reproduces what was played to within the tolerance, with far fewer points.
+	points := r.Points()
+	if start, end := points[0].Time, points[len(points)-1].Time; start != 0 || end != 2 {
+		t.Errorf("recorded from %v to %v, want 0 to 2", start, end)
+	}
+	for i, y := range y {
+		if x := interpolateLinear(points, float64(i+1)/sr); math.Abs(x-y) > tolerance+1e-9 {
+			t.Fatalf("sample %d:  recorded %v, played %v", i, x, y)
+		}
+	}
+	if len(points) > len(y)/10 {
+		t.Errorf("recorded %d points for %d samples", len(points), len(y))
+	}
+}
+
+func TestAutomationRecorderConcurrent(t *testing.T) {
+	r := NewAutomationRecorder(0, 0)
+	Init(r, Params{1000})
+	stop := make(chan bool)
+	done := make(chan bool)
+	go func() {
+		for i := 0; ; i++ {
+			select {
+			case <-stop:
+				done <- true
+				return
+			default:
+			}
+			r.Set(float64(i % 10))
+			r.Points()
+			r.GetTime()
+		}
+	}()
+	for i := 0; i < 10000; i++ {
+		r.Sing()
+	}
+	close(stop)
+	<-done
+	points := r.Points()
+	for i := 1; i < len(points); i++ {
+		if points[i].Time < points[i-1].Time {
+			t.Fatalf("points out of order at %d", i)
+		}
+	}
+	if end := points[len(points)-1].Time; end != 10 {
+		t.Errorf("recorded until %v, want 10", end)
+	}
+}
+
+func TestSimplifyControlPoints(t *testing.T) {
+	rand := NewFastRand(1)
+	var walk, corner []*ControlPoint
+	x := 0.0
+	for i := 0; i <= 1000; i++ {
+		x += rand.Bipolar()
+		walk = append(walk, &ControlPoint{Time: float64(i) / 10, Value: x})
+		corner = append(corner, &ControlPoint{Time: float64(i), Value: math.Abs(float64(i) - 300)})
+	}
+	for _, points := range [][]*ControlPoint{walk, corner} {
+		for _, tolerance := range []float64{0, .1, 1, 10} {
+			s := SimplifyControlPoints(points, tolerance)
+			if s[0] != points[0] || s[len(s)-1] != points[len(points)-1] {
+				t.Errorf("tolerance %v:  the first and last points were not kept", tolerance)
+			}
+			// Every dropped point lies within tolerance of the line through the points kept around it.
+			for _, p := range points {
+				if x := interpolateLinear(s, p.Time); math.Abs(x-p.Value) > tolerance+1e-9 {
+					t.Errorf("tolerance %v:  point at time %v is %v from the simplified line", tolerance, p.Time, math.Abs(x-p.Value))
+					break
+				}
+			}
+		}
+	}
+	if s := SimplifyControlPoints(corner, .01); len(s) != 3 {
+		t.Errorf("expected only the corner to be kept, got %d points", len(s))
+	}
+}
+
+// interpolateLinear returns the value at time t of the line through points.
+func interpolateLinear(points []*ControlPoint, t float64) float64 {
+	i := sort.Search(len(points), func(i int) bool { return points[i].Time > t })
+	if i == 0 {
+		return points[0].Value
+	}
+	if i == len(points) {
+		return points[i-1].Value
+	}
+	a, b := points[i-1], points[i]
+	return a.Value + (b.Value-a.Value)*(t-a.Time)/(b.Time-a.Time)
+}
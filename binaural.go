@@ -0,0 +1,137 @@
+package audio
+
+import "math"
+
+// Binaural places a mono signal around a listener's head for headphone listening.  It uses the spherical head
+// model of Brown and Duda (1998):  each ear hears the signal after an interaural time delay and through a
+// head-shadow filter that provides the interaural level difference, and a few short pinna reflections whose
+// delays depend on the direction of the source provide elevation cues.
+//
+// The direction may be changed every sample; it is smoothed internally to avoid zipper noise.
+type Binaural struct {
+	params     Params
+	HeadRadius float64 // meters
+
+	dir     Vec3 // unit vector towards the source
+	smooth  [3]*ExpSmoother
+	started bool
+	delay   Delay
+	ears    [2]binauralEar
+}
+
+type binauralEar struct {
+	side   float64 // -1 for left, 1 for right
+	x1, y1 float64
+}
+
+// Pinna reflection coefficients and delays (in samples at 44100 Hz) from Brown and Duda.
+var (
+	pinnaRho = [...]float64{.5, -1, .5, -.25, .25}
+	pinnaA   = [...]float64{1, 5, 5, 5, 5}
+	pinnaB   = [...]float64{2, 4, 7, 11, 13}
+	pinnaD   = [...]float64{1, .5, .5, .5, .5}
+)
+
+func NewBinaural() *Binaural {
+	b := &Binaural{HeadRadius: earDistance / 2, dir: Vec3{Y: 1}}
+	for i := range b.smooth {
+		b.smooth[i] = NewExpSmoother(.005, .005)
+	}
+	b.ears[0].side = -1
+	b.ears[1].side = 1
+	return b
+}
+
+func (b *Binaural) InitAudio(p Params) {
+	b.params = p
+	b.delay.Params = p
+	for _, s := range b.smooth {
+		Init(s, p)
+	}
+}
+
+// Direction sets the direction of the source, in radians.  Azimuth 0 is straight ahead and increases to the
+// right; elevation 0 is level and increases upwards.
+func (b *Binaural) Direction(azimuth, elevation float64) *Binaural {
+	b.dir = Vec3{math.Sin(azimuth) * math.Cos(elevation), math.Cos(azimuth) * math.Cos(elevation), math.Sin(elevation)}
+	return b
+}
+
+func (b *Binaural) Process(x float64) (float64, float64) {
+	if !b.started {
+		// jump to the initial direction
+		b.smooth[0].y, b.smooth[1].y, b.smooth[2].y = b.dir.X, b.dir.Y, b.dir.Z
+		b.started = true
+	}
+	u := Vec3{b.smooth[0].Smooth(b.dir.X), b.smooth[1].Smooth(b.dir.Y), b.smooth[2].Smooth(b.dir.Z)}
+	// Normalize, except near the origin (when the source jumps to the opposite direction), where the
+	// smoothed direction passes through the middle of the head instead of flipping instantly.
+	u = u.Scale(1 / math.Max(u.Len(), .5))
+	azimuth := math.Atan2(u.X, u.Y)
+	elevation := math.Asin(math.Max(-1, math.Min(1, u.Z)))
+
+	var out [2]float64
+	for i := range b.ears {
+		out[i] = b.ears[i].process(b, u, azimuth, elevation)
+	}
+	b.delay.Write(x)
+	return out[0], out[1]
+}
+
+func (e *binauralEar) process(b *Binaural, u Vec3, azimuth, elevation float64) float64 {
+	const (
+		alphaMin = .1
+		thetaMin = 150 * math.Pi / 180
+	)
+	a_c := b.HeadRadius / SpeedOfSound
+	minDelay := 2 / b.params.SampleRate
+
+	// theta is the angle between the ear and the source.
+	theta := math.Acos(math.Max(-1, math.Min(1, e.side*u.X)))
+
+	// interaural time delay, offset so that it is never negative
+	itd := a_c
+	if theta < math.Pi/2 {
+		itd -= a_c * math.Cos(theta)
+	} else {
+		itd += a_c * (theta - math.Pi/2)
+	}
+
+	// pinna reflections
+	az := e.side * azimuth
+	x := b.delay.Read(minDelay + itd)
+	for k, rho := range pinnaRho {
+		tau := (pinnaA[k]*math.Cos(az/2)*math.Sin(pinnaD[k]*(math.Pi/2-elevation)) + pinnaB[k]) / 44100
+		x += rho * b.delay.Read(minDelay+itd+tau)
+	}
+
+	// head shadow:  H(s) = (alpha*s + beta) / (s + beta), discretized with the bilinear transform
+	alpha := 1 + alphaMin/2 + (1-alphaMin/2)*math.Cos(theta/thetaMin*math.Pi)
+	beta := 2 / a_c
+	k := 2 * b.params.SampleRate
+	b0 := (alpha*k + beta) / (k + beta)
+	b1 := (beta - alpha*k) / (k + beta)
+	a1 := (beta - k) / (k + beta)
+	y := b0*x + b1*e.x1 - a1*e.y1
+	e.x1, e.y1 = x, y
+	return y
+}
+
+// BinauralVoice is a StereoVoice that places a mono Voice in the direction given by its Azimuth and Elevation Controls.
+type BinauralVoice struct {
+	Voice              Voice
+	Azimuth, Elevation Control
+	Binaural           *Binaural
+}
+
+func NewBinauralVoice(v Voice) *BinauralVoice {
+	return &BinauralVoice{Voice: v, Binaural: NewBinaural()}
+}
+
+func (v *BinauralVoice) Sing() (float64, float64) {
+	return v.Binaural.Direction(v.Azimuth.Sing(), v.Elevation.Sing()).Process(v.Voice.Sing())
+}
+
+func (v *BinauralVoice) Done() bool {
+	return v.Voice.Done()
+}
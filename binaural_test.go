@@ -0,0 +1,90 @@
+package audio
+
+import (
+	"math"
+	"testing"
+)
+
+func TestBinauralITD(t *testing.T) {
+	const sampleRate = 96000
+	b := NewBinaural().Direction(math.Pi/2, 0)
+	Init(b, Params{sampleRate})
+	var onset [2]int
+	var energy [2]float64
+	for n := 0; n < 1000; n++ {
+		x := 0.0
+		if n == 0 {
+			x = 1
+		}
+		l, r := b.Process(x)
+		for i, y := range []float64{l, r} {
+			if onset[i] == 0 && math.Abs(y) > .01 {
+				onset[i] = n
+			}
+			energy[i] += y * y
+		}
+	}
+	a_c := b.HeadRadius / SpeedOfSound
+	itd := float64(onset[0]-onset[1]) / sampleRate
+	if want := a_c * (1 + math.Pi/2); math.Abs(itd-want) > 2./sampleRate {
+		t.Errorf("expected ITD %g s, got %g s", want, itd)
+	}
+	if energy[1] < 2*energy[0] {
+		t.Errorf("expected the right ear to be louder, got energies %g (left) and %g (right)", energy[0], energy[1])
+	}
+}
+
+func TestBinauralAhead(t *testing.T) {
+	b := NewBinaural().Direction(0, .3)
+	Init(b, Params{48000})
+	rand := NewFastRand(1)
+	first, constant := rand.Bipolar(), true
+	for n := 0; n < 4800; n++ {
+		x := rand.Bipolar()
+		constant = constant && x == first
+		if l, r := b.Process(x); l != r {
+			t.Fatalf("sample %d: left %g != right %g", n, l, r)
+		}
+	}
+	if constant {
+		t.Error("expected varying input")
+	}
+}
+
+func TestBinauralSweep(t *testing.T) {
+	const sampleRate = 48000
+	// maxStep returns the largest change between consecutive outputs for a 100 Hz sine, with the direction
+	// set by dir each sample.
+	maxStep := func(dir func(n int) float64) float64 {
+		b := NewBinaural()
+		Init(b, Params{sampleRate})
+		var prev [2]float64
+		max := 0.0
+		for n := 0; n < sampleRate; n++ {
+			b.Direction(dir(n), 0)
+			l, r := b.Process(math.Sin(2 * math.Pi * 100 * float64(n) / sampleRate))
+			if n > sampleRate/10 {
+				max = math.Max(max, math.Max(math.Abs(l-prev[0]), math.Abs(r-prev[1])))
+			}
+			prev = [2]float64{l, r}
+		}
+		return max
+	}
+	still := maxStep(func(int) float64 { return 0 })
+	sweep := maxStep(func(n int) float64 { return 2 * math.Pi * float64(n) / sampleRate })
+	jump := maxStep(func(n int) float64 {
+		if n%(sampleRate/10) < sampleRate/20 {
+			return -math.Pi / 2
+		}
+		return math.Pi / 2
+	})
+	t.Logf("largest step:  still %g, sweeping %g, jumping %g", still, sweep, jump)
+	// A click would be a step of the order of the amplitude.  Jumping to the opposite side is smoothed
+	// over a few milliseconds, which briefly shifts the pitch and level but does not click.
+	if sweep > 1.1*still {
+		t.Errorf("expected sweeping to change the largest step by less than 10%%, got %g vs %g", sweep, still)
+	}
+	if jump > 3*still {
+		t.Errorf("expected jumping to at most triple the largest step, got %g vs %g", jump, still)
+	}
+}
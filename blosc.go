@@ -0,0 +1,236 @@
+package audio
+
+import "math"
+
+// The oscillators in this file are band-limited using PolyBLEP (polynomial band-limited step) and PolyBLAMP
+// (band-limited ramp) residuals, which smooth the discontinuities in the waveform or its slope over the two
+// surrounding samples.  This greatly reduces aliasing compared to naive waveforms such as SawOsc.
+
+// blPhase is a phase accumulator running from 0 to 1.
+type blPhase struct {
+	dt      float64
+	freq    float64
+	p       float64
+	dp      float64
+	wrapped bool
+}
+
+func (o *blPhase) initAudio(p Params) {
+	o.dt = 1 / p.SampleRate
+	o.setFreq(o.freq)
+}
+
+func (o *blPhase) setFreq(freq float64) {
+	o.freq = freq
+	o.dp = math.Abs(freq) * o.dt
+}
+
+func (o *blPhase) setPhase(x float64) {
+	o.p = x - math.Floor(x)
+}
+
+// reset sets the phase of the next step.
+func (o *blPhase) reset(x float64) {
+	o.p = x - math.Floor(x) - o.dp
+}
+
+func (o *blPhase) step() float64 {
+	o.p += o.dp
+	o.wrapped = o.p >= 1
+	if o.wrapped {
+		o.p -= math.Floor(o.p)
+	}
+	return o.p
+}
+
+func (o *blPhase) wrappedAgo() (float64, bool) {
+	if !o.wrapped || o.dp == 0 {
+		return 0, false
+	}
+	return o.p / o.dp, true
+}
+
+// polyBLEP returns the residual of an upward step of height 2 at phase 0, for phase t and phase increment dt.
+func polyBLEP(t, dt float64) float64 {
+	if t < dt {
+		t /= dt
+		return -(t - 1) * (t - 1)
+	}
+	if t > 1-dt {
+		t = (t - 1) / dt
+		return (t + 1) * (t + 1)
+	}
+	return 0
+}
+
+// polyBLAMP returns the residual of a unit ramp (slope 1 per sample) starting at phase 0, for phase t and phase increment dt.
+func polyBLAMP(t, dt float64) float64 {
+	if t < dt {
+		t = t/dt - 1
+		return -t * t * t / 6
+	}
+	if t > 1-dt {
+		t = (t-1)/dt + 1
+		return t * t * t / 6
+	}
+	return 0
+}
+
+func wrap(p float64) float64 {
+	return p - math.Floor(p)
+}
+
+// BLSawOsc is a band-limited sawtooth oscillator, rising from -1 to 1.
+type BLSawOsc struct {
+	ph blPhase
+}
+
+func (o *BLSawOsc) InitAudio(p Params) { o.ph.initAudio(p) }
+
+func (o *BLSawOsc) Freq(freq float64) *BLSawOsc {
+	o.ph.setFreq(freq)
+	return o
+}
+
+func (o *BLSawOsc) Phase(x float64) *BLSawOsc {
+	o.ph.setPhase(x)
+	return o
+}
+
+// Reset sets the phase (0..1) of the next sample, for deterministic retriggering.
+func (o *BLSawOsc) Reset(phase float64) *BLSawOsc {
+	o.ph.reset(phase)
+	return o
+}
+
+func (o *BLSawOsc) Sing() float64 {
+	p := o.ph.step()
+	return 2*p - 1 - polyBLEP(p, o.ph.dp)
+}
+
+// Wrapped implements SyncMaster.
+func (o *BLSawOsc) Wrapped() (float64, bool) { return o.ph.wrappedAgo() }
+
+// Sync implements SyncSlave.
+func (o *BLSawOsc) Sync(ago float64) (y, jump float64) {
+	before := 2*wrap(o.ph.p-ago*o.ph.dp) - 1
+	o.ph.p = ago * o.ph.dp
+	return 2*o.ph.p - 1, -1 - before
+}
+
+// SquareOsc is a band-limited square wave oscillator, starting at 1 for the first half of each cycle.
+type SquareOsc struct {
+	ph blPhase
+}
+
+func (o *SquareOsc) InitAudio(p Params) { o.ph.initAudio(p) }
+
+func (o *SquareOsc) Freq(freq float64) *SquareOsc {
+	o.ph.setFreq(freq)
+	return o
+}
+
+func (o *SquareOsc) Phase(x float64) *SquareOsc {
+	o.ph.setPhase(x)
+	return o
+}
+
+// Reset sets the phase (0..1) of the next sample, for deterministic retriggering.
+func (o *SquareOsc) Reset(phase float64) *SquareOsc {
+	o.ph.reset(phase)
+	return o
+}
+
+// Wrapped implements SyncMaster.
+func (o *SquareOsc) Wrapped() (float64, bool) { return o.ph.wrappedAgo() }
+
+func (o *SquareOsc) Sing() float64 {
+	p := o.ph.step()
+	return pulse(p, o.ph.dp, .5)
+}
+
+// PulseOsc is a band-limited pulse wave oscillator, at 1 for the first Width of each cycle and -1 for the rest.
+// The width may be modulated every sample.  It defaults to .5 (a square wave).
+type PulseOsc struct {
+	ph    blPhase
+	width float64
+}
+
+func (o *PulseOsc) InitAudio(p Params) {
+	o.ph.initAudio(p)
+	if o.width == 0 {
+		o.width = .5
+	}
+}
+
+func (o *PulseOsc) Freq(freq float64) *PulseOsc {
+	o.ph.setFreq(freq)
+	return o
+}
+
+func (o *PulseOsc) Phase(x float64) *PulseOsc {
+	o.ph.setPhase(x)
+	return o
+}
+
+// Width sets the fraction (0..1) of each cycle during which the output is high.
+func (o *PulseOsc) Width(w float64) *PulseOsc {
+	o.width = math.Max(0, math.Min(1, w))
+	return o
+}
+
+// Reset sets the phase (0..1) of the next sample, for deterministic retriggering.
+func (o *PulseOsc) Reset(phase float64) *PulseOsc {
+	o.ph.reset(phase)
+	return o
+}
+
+// Wrapped implements SyncMaster.
+func (o *PulseOsc) Wrapped() (float64, bool) { return o.ph.wrappedAgo() }
+
+func (o *PulseOsc) Sing() float64 {
+	p := o.ph.step()
+	return pulse(p, o.ph.dp, o.width)
+}
+
+func pulse(p, dp, width float64) float64 {
+	y := -1.0
+	if p < width {
+		y = 1
+	}
+	return y + polyBLEP(p, dp) - polyBLEP(wrap(p-width), dp)
+}
+
+// TriangleOsc is a band-limited triangle wave oscillator, rising from -1 to 1 in the first half of each cycle.
+type TriangleOsc struct {
+	ph blPhase
+}
+
+func (o *TriangleOsc) InitAudio(p Params) { o.ph.initAudio(p) }
+
+func (o *TriangleOsc) Freq(freq float64) *TriangleOsc {
+	o.ph.setFreq(freq)
+	return o
+}
+
+func (o *TriangleOsc) Phase(x float64) *TriangleOsc {
+	o.ph.setPhase(x)
+	return o
+}
+
+// Reset sets the phase (0..1) of the next sample, for deterministic retriggering.
+func (o *TriangleOsc) Reset(phase float64) *TriangleOsc {
+	o.ph.reset(phase)
+	return o
+}
+
+// Wrapped implements SyncMaster.
+func (o *TriangleOsc) Wrapped() (float64, bool) { return o.ph.wrappedAgo() }
+
+func (o *TriangleOsc) Sing() float64 {
+	p := o.ph.step()
+	dp := o.ph.dp
+	y := 1 - 4*math.Abs(p-.5)
+	// The slope changes by +8 (per cycle) at phase 0 and by -8 at phase .5.
+	return y + 8*dp*(polyBLAMP(p, dp)-polyBLAMP(wrap(p+.5), dp))
+}
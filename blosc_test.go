@@ -0,0 +1,78 @@
+package audio
+
+import (
+	"math"
+	"math/cmplx"
+	"testing"
+)
+
+func TestBandLimitedOscs(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		osc  interface{ Sing() float64 }
+		// maximum aliasing energy relative to total energy, in dB
+		maxAliasing float64
+	}{
+		{"SawOsc", new(SawOsc), 0},
+		{"BLSawOsc", new(BLSawOsc), -22},
+		{"SquareOsc", new(SquareOsc), -25},
+		{"PulseOsc", new(PulseOsc).Width(.3), -22},
+		{"TriangleOsc", new(TriangleOsc), -45},
+	} {
+		aliasing := aliasingEnergy(test.osc)
+		t.Logf("%s: aliasing %.1f dB", test.name, aliasing)
+		if aliasing > test.maxAliasing {
+			t.Errorf("%s: expected aliasing below %.f dB, got %.1f dB", test.name, test.maxAliasing, aliasing)
+		}
+	}
+
+	if naive, bl := aliasingEnergy(new(SawOsc)), aliasingEnergy(new(BLSawOsc)); bl > naive-12 {
+		t.Errorf("expected BLSawOsc to alias at least 12 dB less than SawOsc, got %.1f dB vs %.1f dB", bl, naive)
+	}
+}
+
+// aliasingEnergy plays osc at a frequency whose harmonics fall exactly on FFT bins and returns the energy in
+// bins away from the harmonics, relative to the total energy, in dB.
+func aliasingEnergy(osc interface{ Sing() float64 }) float64 {
+	const (
+		sampleRate = 48000
+		size       = 8192
+		bin        = 523
+	)
+	freq := float64(bin) * sampleRate / size
+	Init(osc, Params{sampleRate})
+	switch o := osc.(type) {
+	case *SawOsc:
+		o.Freq(freq)
+	case *BLSawOsc:
+		o.Freq(freq)
+	case *SquareOsc:
+		o.Freq(freq)
+	case *PulseOsc:
+		o.Freq(freq)
+	case *TriangleOsc:
+		o.Freq(freq)
+	case *WavetableOsc:
+		o.Freq(freq)
+	case *syncOsc:
+		o.Freq(freq)
+	}
+
+	x := make([]complex128, size)
+	for i := range x {
+		window := (1 - math.Cos(2*math.Pi*float64(i)/size)) / 2
+		x[i] = complex(window*osc.Sing(), 0)
+	}
+	x = NewFFT(size, nil).fft.Transform(x)
+
+	total, aliasing := 0.0, 0.0
+	for i, x := range x[1 : size/2] {
+		i++
+		e := math.Pow(cmplx.Abs(x), 2)
+		total += e
+		if d := i % bin; d > 2 && d < bin-2 {
+			aliasing += e
+		}
+	}
+	return 10 * math.Log10(aliasing/total)
+}
@@ -1,18 +1,48 @@
 package audio
 
-import "fmt"
+import (
+	"fmt"
+	"math"
+	"sort"
+)
 
+// A Control plays a sequence of ControlPoints.  It can be played in reverse (see SetReverse) and can loop over
+// a region (see SetLoop).
 type Control struct {
-	params  Params
-	points  []*ControlPoint
-	periods []*controlPeriod
-	x       float64
+	params             Params
+	points             []*ControlPoint
+	k                  int // the current time, in samples
+	i                  int // the index of the first point after the current time
+	reverse            bool
+	bounced            bool // on the return leg of a ping-pong loop, opposite to reverse
+	loop               bool
+	pingPong           bool
+	loopStart, loopEnd float64
+	x                  float64
 }
 
+// A ControlPoint is a value at a time.  Write ControlPoint literals with keys (e.g.,
+// ControlPoint{Time: 0, Value: 1}); fields may be added.
 type ControlPoint struct {
 	Time, Value float64
+	// Interp is how the value moves from the previous point to this one.
+	Interp Interpolation
+	// Tension applies to CubicInterp.  0 gives a Catmull-Rom spline; 1 flattens the curve at each point;
+	// negative values overshoot more.
+	Tension float64
 }
 
+// Interpolation is the shape of the change in a Control's value between two points.
+type Interpolation int
+
+const (
+	LinearInterp Interpolation = iota
+	StepInterp                 // hold the previous value, then jump at the point
+	ExpInterp                  // change geometrically, e.g. for frequency or gain; linear if the values differ in sign or either is zero
+	CosineInterp               // ease in and out along a half cosine
+	CubicInterp                // a smooth spline through the neighboring points, shaped by Tension
+)
+
 func NewControl(points []*ControlPoint) *Control {
 	return &Control{points: points}
 }
@@ -40,49 +70,151 @@ func (c *Control) Duration() float64 {
 	return c.points[len(c.points)-1].Time
 }
 
+// GetTime returns the current time.
+func (c *Control) GetTime() float64 { return float64(c.k) / c.params.SampleRate }
+
+// SetTime moves to time t, in O(log n) for n points.
 func (c *Control) SetTime(t float64) {
-	c.periods = make([]*controlPeriod, len(c.points))
-	prev := &ControlPoint{}
-	for i, p := range c.points {
-		dn := (p.Time - prev.Time) * c.params.SampleRate
-		dx := (p.Value - prev.Value) / dn
-		c.periods[i] = &controlPeriod{int(dn), dx, p.Value}
-		prev = p
-	}
+	c.k = int(t * c.params.SampleRate)
+	c.i = c.search(float64(c.k) / c.params.SampleRate)
+	c.x = c.value(float64(c.k)/c.params.SampleRate, c.i)
+}
 
-	c.x = 0
-	n := int(t * c.params.SampleRate)
-	for _, p := range c.periods {
-		if p.n > n {
-			p.n -= n
-			c.x += float64(n) * p.dx
-			break
-		}
-		n -= p.n
-		c.x = p.value
-		c.periods = c.periods[1:]
-	}
+// SetReverse sets whether the control plays backward in time.  Played in reverse, it is done when it reaches
+// time 0.
+func (c *Control) SetReverse(reverse bool) {
+	c.reverse = reverse
+}
+
+// SetLoop loops the control over the region between times start and end, once it reaches it.  Normally it
+// jumps from one end of the region to the other; if pingPong is true, it reverses direction at each end.
+// A looping control is never done, unless it has already passed the region, in which case it plays on as if
+// it weren't looping.
+func (c *Control) SetLoop(start, end float64, pingPong bool) {
+	c.loopStart, c.loopEnd = start, end
+	c.loop = end > start
+	c.pingPong = pingPong
+	c.bounced = false
+}
+
+// NoLoop ends looping.  The control continues from its current time, in the direction set by SetReverse.
+func (c *Control) NoLoop() {
+	c.loop = false
+	c.bounced = false
+}
+
+// ValueAt returns the value at time t, without affecting playback.
+func (c *Control) ValueAt(t float64) float64 {
+	return c.value(t, c.search(t))
 }
 
 func (c *Control) Sing() float64 {
-	for _, p := range c.periods {
-		if p.n > 0 {
-			p.n--
-			c.x += p.dx
-			break
+	back := c.reverse != c.bounced
+	if back {
+		c.k--
+	} else {
+		c.k++
+	}
+	if from, to := c.loopRegion(); c.loop && to > from {
+		switch {
+		case !back && c.pingPong && c.k == to+1:
+			c.k, c.bounced = to-1, !c.bounced
+		case !back && !c.pingPong && c.k == to:
+			c.k = from
+		case back && c.pingPong && c.k == from-1:
+			c.k, c.bounced = from+1, !c.bounced
+		case back && !c.pingPong && c.k == from-1:
+			c.k = to - 1
 		}
-		c.x = p.value // this is necessary for zero-length controlPeriods that mark discontinuities
-		c.periods = c.periods[1:]
 	}
+	t := float64(c.k) / c.params.SampleRate
+	for c.i < len(c.points) && c.points[c.i].Time <= t {
+		c.i++
+	}
+	for c.i > 0 && c.points[c.i-1].Time > t {
+		c.i--
+	}
+	c.x = c.value(t, c.i)
 	return c.x
 }
 
+// Done reports whether the control has reached its last point (or, in reverse, time 0).
 func (c *Control) Done() bool {
-	return len(c.periods) == 0
+	if len(c.points) == 0 {
+		return true
+	}
+	if from, to := c.loopRegion(); c.loop && to > from {
+		if back := c.reverse != c.bounced; back && c.k >= from || !back && c.k <= to {
+			return false
+		}
+	}
+	if c.reverse {
+		return c.k <= 0
+	}
+	return c.k >= int(c.Duration()*c.params.SampleRate)
+}
+
+// loopRegion returns the start and end of the loop, in samples.
+func (c *Control) loopRegion() (from, to int) {
+	return int(c.loopStart * c.params.SampleRate), int(c.loopEnd * c.params.SampleRate)
+}
+
+// search returns the index of the first point after time t.
+func (c *Control) search(t float64) int {
+	return sort.Search(len(c.points), func(i int) bool { return c.points[i].Time > t })
+}
+
+// value returns the value at time t, where i is the index of the first point after t.
+func (c *Control) value(t float64, i int) float64 {
+	if i == len(c.points) {
+		if i == 0 {
+			return 0
+		}
+		return c.points[i-1].Value
+	}
+	p0, p1 := c.point(i-1), c.points[i]
+	u := 0.0
+	if p1.Time > p0.Time {
+		u = math.Max(0, math.Min(1, (t-p0.Time)/(p1.Time-p0.Time)))
+	}
+	switch p1.Interp {
+	case StepInterp:
+		return p0.Value
+	case ExpInterp:
+		if p0.Value*p1.Value > 0 {
+			return p0.Value * math.Pow(p1.Value/p0.Value, u)
+		}
+	case CosineInterp:
+		u = (1 - math.Cos(math.Pi*u)) / 2
+	case CubicInterp:
+		d := p1.Time - p0.Time
+		m0, m1 := d*c.slope(i-1, p1.Tension), d*c.slope(i, p1.Tension)
+		u2, u3 := u*u, u*u*u
+		return (2*u3-3*u2+1)*p0.Value + (u3-2*u2+u)*m0 + (-2*u3+3*u2)*p1.Value + (u3-u2)*m1
+	}
+	return p0.Value + (p1.Value-p0.Value)*u
+}
+
+// slope returns the tangent of a cubic spline at point i, from its neighbors.  Point -1 is the implicit
+// starting point, zero at time 0.
+func (c *Control) slope(i int, tension float64) float64 {
+	p0, p1 := c.point(i-1), c.point(i+1)
+	dt := p1.Time - p0.Time
+	if dt <= 0 {
+		return 0
+	}
+	return (1 - tension) * (p1.Value - p0.Value) / dt
 }
 
-type controlPeriod struct {
-	n     int
-	dx    float64
-	value float64
+func (c *Control) point(i int) *ControlPoint {
+	if i >= len(c.points) {
+		i = len(c.points) - 1
+	}
+	if i < 0 {
+		return &controlStart
+	}
+	return c.points[i]
 }
+
+// controlStart is the implicit point at which every Control starts.
+var controlStart ControlPoint
@@ -0,0 +1,158 @@
+package audio
+
+import (
+	"math"
+	"testing"
+)
+
+func TestControlInterp(t *testing.T) {
+	const sr = 100
+	for _, c := range []struct {
+		interp  Interpolation
+		tension float64
+		mid     float64 // the value halfway from 1 to 4
+	}{
+		{LinearInterp, 0, 2.5},
+		{StepInterp, 0, 1},
+		{ExpInterp, 0, 2},
+		{CosineInterp, 0, 2.5},
+		{CubicInterp, 1, 2.5},
+	} {
+		ctrl := NewControl([]*ControlPoint{{Time: 0, Value: 1}, {Time: 1, Value: 4, Interp: c.interp, Tension: c.tension}})
+		Init(ctrl, Params{sr})
+		var y []float64
+		for !ctrl.Done() {
+			y = append(y, ctrl.Sing())
+		}
+		if len(y) != sr {
+			t.Fatalf("%v: got %d samples, want %d", c.interp, len(y), sr)
+		}
+		// y[i] is the value at time (i+1)/sr.
+		if math.Abs(y[sr/2-1]-c.mid) > 1e-9 || y[sr-1] != 4 {
+			t.Errorf("%v: got %v halfway and %v at the end, want %v and 4", c.interp, y[sr/2-1], y[sr-1], c.mid)
+		}
+		ctrl.SetTime(.5)
+		if x := ctrl.x; math.Abs(x-c.mid) > 1e-9 {
+			t.Errorf("%v: after SetTime(.5), value is %v, want %v", c.interp, x, c.mid)
+		}
+	}
+
+	// A Catmull-Rom spline passes through the points with continuous slope.
+	ctrl := NewControl([]*ControlPoint{{Time: 0, Value: 0}, {Time: 1, Value: 1, Interp: CubicInterp}, {Time: 2, Value: 0, Interp: CubicInterp}})
+	Init(ctrl, Params{sr})
+	var y []float64
+	for !ctrl.Done() {
+		y = append(y, ctrl.Sing())
+	}
+	if y[sr-1] != 1 {
+		t.Errorf("spline at point = %v, want 1", y[sr-1])
+	}
+	if d1, d2 := y[sr-1]-y[sr-2], y[sr]-y[sr-1]; math.Abs(d1+d2) > 1e-3 {
+		t.Errorf("spline slope is not continuous at peak:  %v, %v", d1, d2)
+	}
+}
+
+func TestControlSeek(t *testing.T) {
+	const sr = 100
+	var points []*ControlPoint
+	for i := 0; i <= 1000; i++ {
+		points = append(points, &ControlPoint{Time: float64(i), Value: float64(i % 2)})
+	}
+	ctrl := NewControl(points)
+	Init(ctrl, Params{sr})
+
+	for _, tm := range []float64{500.25, 3.5, 999.75, 0, 1000, 20} {
+		want := ctrl.ValueAt(tm)
+		ctrl.SetTime(tm)
+		if ctrl.x != want {
+			t.Errorf("SetTime(%v):  value is %v, want %v", tm, ctrl.x, want)
+		}
+		if y, want := ctrl.Sing(), ctrl.ValueAt(tm+1./sr); math.Abs(y-want) > 1e-9 {
+			t.Errorf("after SetTime(%v):  Sing() = %v, want %v", tm, y, want)
+		}
+	}
+	if x := ctrl.ValueAt(2.25); x != .25 {
+		t.Errorf("ValueAt(2.25) = %v, want .25", x)
+	}
+	if n := testing.AllocsPerRun(100, func() { ctrl.SetTime(123.45); ctrl.Sing() }); n != 0 {
+		t.Errorf("SetTime allocates %v times", n)
+	}
+}
+
+func TestControlLoop(t *testing.T) {
+	const sr = 10
+	sing := func(ctrl *Control, n int) (y []float64) {
+		for i := 0; i < n; i++ {
+			y = append(y, ctrl.Sing())
+		}
+		return
+	}
+	equal := func(a, b []float64) bool {
+		if len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if math.Abs(a[i]-b[i]) > 1e-9 {
+				return false
+			}
+		}
+		return true
+	}
+	ramp := []*ControlPoint{{Time: 0, Value: 0}, {Time: 1, Value: 10}}
+
+	ctrl := NewControl(ramp)
+	Init(ctrl, Params{sr})
+	ctrl.SetLoop(.2, .5, false)
+	if y, want := sing(ctrl, 10), []float64{1, 2, 3, 4, 2, 3, 4, 2, 3, 4}; !equal(y, want) {
+		t.Errorf("loop:  got %v, want %v", y, want)
+	}
+	if ctrl.Done() {
+		t.Error("looping control is done")
+	}
+	ctrl.NoLoop()
+	sing(ctrl, 5)
+	if y := sing(ctrl, 1); y[0] != 10 || !ctrl.Done() {
+		t.Errorf("after loop:  got %v (done %v), want 10", y, ctrl.Done())
+	}
+
+	ctrl.SetTime(0)
+	ctrl.SetLoop(.2, .5, true)
+	if y, want := sing(ctrl, 10), []float64{1, 2, 3, 4, 5, 4, 3, 2, 3, 4}; !equal(y, want) {
+		t.Errorf("ping-pong loop:  got %v, want %v", y, want)
+	}
+
+	// Ending the loop on its return leg continues forward from there.
+	if y, want := sing(ctrl, 2), []float64{5, 4}; !equal(y, want) {
+		t.Errorf("ping-pong loop:  got %v, want %v", y, want)
+	}
+	ctrl.NoLoop()
+	if y, want := sing(ctrl, 2), []float64{5, 6}; !equal(y, want) {
+		t.Errorf("after ping-pong loop:  got %v, want %v", y, want)
+	}
+
+	// Ping-pong loops in reverse bounce forward from the loop start.
+	ctrl.SetReverse(true)
+	ctrl.SetTime(.8)
+	ctrl.SetLoop(.2, .5, true)
+	if y, want := sing(ctrl, 8), []float64{7, 6, 5, 4, 3, 2, 3, 4}; !equal(y, want) {
+		t.Errorf("reverse ping-pong loop:  got %v, want %v", y, want)
+	}
+	ctrl.NoLoop()
+	ctrl.SetTime(.3)
+	if y, want := sing(ctrl, 3), []float64{2, 1, 0}; !equal(y, want) || !ctrl.Done() {
+		t.Errorf("reverse:  got %v (done %v), want %v", y, ctrl.Done(), want)
+	}
+	// A loop that has already been passed is ignored, so the control still finishes.
+	ctrl.SetReverse(false)
+	ctrl.SetTime(.7)
+	ctrl.SetLoop(.2, .5, false)
+	if y, want := sing(ctrl, 3), []float64{8, 9, 10}; !equal(y, want) || !ctrl.Done() {
+		t.Errorf("after passed loop:  got %v (done %v), want %v", y, ctrl.Done(), want)
+	}
+	ctrl.SetReverse(true)
+	ctrl.SetTime(.1)
+	ctrl.SetLoop(.2, .5, true)
+	if y, want := sing(ctrl, 1), []float64{0}; !equal(y, want) || !ctrl.Done() {
+		t.Errorf("reverse after passed loop:  got %v (done %v), want %v", y, ctrl.Done(), want)
+	}
+}
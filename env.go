@@ -2,10 +2,22 @@ package audio
 
 import "math"
 
+// ExpEnv is an envelope made of a sequence of segments, each of which moves to a level in a given time.
+// Segments added with Go approach their level exponentially; other segment shapes are added with GoCurve,
+// GoSmooth and Hold.
+//
+// An envelope can be held at a sustain point (see Sustain) or repeat a loop of segments (see LoopStart and
+// LoopEnd) until Release is called, after which it continues with the segments that follow.
 type ExpEnv struct {
-	p Params
-	s []expSeg
-	y float64
+	p        Params
+	s        []expSeg
+	i        int // current segment
+	y, from  float64
+	sustain  int // the index of the segment before which to hold until released, or 0 for none
+	loop     bool
+	loopFrom int
+	loopTo   int
+	released bool
 }
 
 func (e *ExpEnv) InitAudio(p Params) {
@@ -14,14 +26,70 @@ func (e *ExpEnv) InitAudio(p Params) {
 }
 
 func (e *ExpEnv) Go(x, t float64) *ExpEnv {
-	s := expSeg{x: x, t: t}
+	return e.add(expSeg{x: x, t: t})
+}
+
+// GoCurve adds a segment to x in t seconds, shaped by c.
+func (e *ExpEnv) GoCurve(x, t float64, c Curve) *ExpEnv {
+	return e.add(expSeg{kind: curveSeg, x: x, t: t, curve: c})
+}
+
+// GoSmooth adds an S-shaped segment to x in t seconds, which starts and ends with zero slope.
+func (e *ExpEnv) GoSmooth(x, t float64) *ExpEnv {
+	return e.add(expSeg{kind: smoothSeg, x: x, t: t})
+}
+
+// Hold adds a segment that stays at the current level for t seconds.
+func (e *ExpEnv) Hold(t float64) *ExpEnv {
+	return e.add(expSeg{kind: holdSeg, t: t})
+}
+
+func (e *ExpEnv) add(s expSeg) *ExpEnv {
 	Init(&s, e.p)
 	e.s = append(e.s, s)
 	return e
 }
 
+// Name names the last segment added, for Stage.
+func (e *ExpEnv) Name(name string) *ExpEnv {
+	if len(e.s) > 0 {
+		e.s[len(e.s)-1].name = name
+	}
+	return e
+}
+
+// Sustain marks the end of the segments added so far as the sustain point, where the envelope holds
+// until Release is called.  It has no effect before any segments are added.
+func (e *ExpEnv) Sustain() *ExpEnv {
+	e.sustain = len(e.s)
+	return e
+}
+
+// LoopStart marks the start of a loop at the end of the segments added so far.
+func (e *ExpEnv) LoopStart() *ExpEnv {
+	e.loopFrom = len(e.s)
+	return e
+}
+
+// LoopEnd marks the end of a loop at the end of the segments added so far.  Until Release is called, the
+// envelope repeats the segments from LoopStart to here.
+func (e *ExpEnv) LoopEnd() *ExpEnv {
+	e.loop = true
+	e.loopTo = len(e.s)
+	return e
+}
+
+// Release ends any sustain or loop.  The envelope continues with the segments after the sustain point or
+// loop end.
+func (e *ExpEnv) Release() {
+	e.released = true
+	if e.i < len(e.s) && e.s[e.i].done() {
+		e.next()
+	}
+}
+
 func (e *ExpEnv) GoNow(x, t float64) *ExpEnv {
-	e.s = nil
+	e.reset()
 	e.Go(x, t)
 	return e
 }
@@ -31,43 +99,115 @@ func (e *ExpEnv) AttackHoldRelease(a, h, r float64) *ExpEnv {
 }
 
 func (e *ExpEnv) ReleaseNow(t float64) {
-	e.s = nil
+	e.reset()
 	e.Go(0, t)
 }
 
+func (e *ExpEnv) reset() {
+	e.s = nil
+	e.i = 0
+	e.sustain = 0
+	e.loop = false
+	e.released = false
+}
+
+// Stage returns the index and name of the current segment (the last one, once finished), or -1 if there
+// are no segments.
+func (e *ExpEnv) Stage() (int, string) {
+	if len(e.s) == 0 {
+		return -1, ""
+	}
+	return e.i, e.s[e.i].name
+}
+
 func (e *ExpEnv) Sing() float64 {
-	if len(e.s) > 0 {
-		s := &e.s[0]
-		e.y += s.do(e.y)
-		if s.done() && len(e.s) > 1 {
-			e.s = e.s[1:]
+	if e.i < len(e.s) {
+		s := &e.s[e.i]
+		if s.k == 0 {
+			e.from = e.y
+		}
+		e.y = s.do(e.y, e.from)
+		if s.k == s.n {
+			e.next()
 		}
 	}
 	return e.y
 }
 
+// next moves to the next segment, unless held at the sustain point or the end.
+func (e *ExpEnv) next() {
+	j := e.i + 1
+	if !e.released {
+		if j == e.sustain {
+			return
+		}
+		if e.loop && j == e.loopTo && e.loopFrom < e.loopTo {
+			j = e.loopFrom
+		}
+	}
+	if j >= len(e.s) {
+		return
+	}
+	e.i = j
+	e.s[j].k = 0
+}
+
 func (e *ExpEnv) Done() bool {
-	return len(e.s) == 0 || len(e.s) == 1 && math.Abs(e.y-e.s[0].x) < .0001
+	if len(e.s) == 0 {
+		return true
+	}
+	if e.i < len(e.s)-1 || !e.released && (e.sustain == len(e.s) || e.loop && e.loopTo == len(e.s)) {
+		return false
+	}
+	s := &e.s[e.i]
+	if s.kind == approachSeg {
+		return math.Abs(e.y-s.x) < .0001
+	}
+	return s.done()
 }
 
+type segKind int
+
+const (
+	approachSeg segKind = iota
+	curveSeg
+	smoothSeg
+	holdSeg
+)
+
 type expSeg struct {
-	x float64
-	t float64
-	n int
-	a float64
+	kind  segKind
+	x     float64
+	t     float64
+	curve Curve
+	name  string
+	n, k  int // length in samples, samples done
+	a     float64
 }
 
 func (s *expSeg) InitAudio(p Params) {
 	n := p.SampleRate * s.t
-	s.n = int(n) - 1
+	s.n = int(math.Max(1, math.Floor(n)))
 	s.a = 1 - math.Pow(.01, 1/n)
 }
 
-func (s *expSeg) do(y float64) float64 {
-	s.n--
-	return s.a * (s.x - y)
+// do advances the segment by a sample from level y, having started at from.
+func (s *expSeg) do(y, from float64) float64 {
+	if s.k < s.n {
+		s.k++
+	}
+	x := float64(s.k) / float64(s.n)
+	switch s.kind {
+	case curveSeg:
+		return from + (s.x-from)*s.curve.shape(x)
+	case smoothSeg:
+		return from + (s.x-from)*(1-math.Cos(math.Pi*x))/2
+	case holdSeg:
+		return y
+	}
+	return y + s.a*(s.x-y)
 }
 
 func (s *expSeg) done() bool {
-	return s.n < 0
+	return s.k >= s.n
 }
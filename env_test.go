@@ -1,6 +1,7 @@
 package audio
 
 import (
+	"math"
 	"testing"
 )
 
@@ -14,3 +15,63 @@ func BenchmarkExpEnv(b *testing.B) {
 		e.Sing()
 	}
 }
+
+func TestExpEnv(t *testing.T) {
+	const sr = 1000
+	sing := func(e *ExpEnv, n int) (y float64) {
+		for i := 0; i < n; i++ {
+			y = e.Sing()
+		}
+		return
+	}
+
+	var e ExpEnv
+	Init(&e, Params{sr})
+	e.GoCurve(1, .1, LinearCurve).Name("attack").GoSmooth(.5, .1).Name("decay").Sustain().Go(0, .1).Name("release")
+	if y := sing(&e, 50); math.Abs(y-.5) > 1e-9 {
+		t.Errorf("linear segment at half time = %v, want .5", y)
+	}
+	if y := sing(&e, 100); math.Abs(y-.75) > 1e-9 {
+		t.Errorf("S-curve segment at half time = %v, want .75", y)
+	}
+	if i, name := e.Stage(); i != 1 || name != "decay" {
+		t.Errorf("stage = %d %q, want 1 \"decay\"", i, name)
+	}
+	if y := sing(&e, 1000); y != .5 || e.Done() {
+		t.Errorf("sustained level = %v (done %v), want .5", y, e.Done())
+	}
+	e.Release()
+	if i, name := e.Stage(); i != 2 || name != "release" {
+		t.Errorf("stage after release = %d %q, want 2 \"release\"", i, name)
+	}
+	if y := sing(&e, 100); math.Abs(y-.005) > 1e-3 {
+		t.Errorf("exponential segment at end = %v, want .005", y)
+	}
+	sing(&e, 100)
+	if !e.Done() {
+		t.Error("not done after release")
+	}
+
+	e = ExpEnv{}
+	Init(&e, Params{sr})
+	e.GoCurve(1, .01, LinearCurve).LoopStart().GoCurve(.5, .01, ExpCurve).Hold(.01).GoCurve(1, .01, LogCurve).LoopEnd().GoCurve(0, .01, LinearCurve)
+	sing(&e, 10)
+	for i := 0; i < 3; i++ {
+		if y := sing(&e, 10); math.Abs(y-.5) > 1e-9 {
+			t.Errorf("loop %d: level = %v, want .5", i, y)
+		}
+		if y := sing(&e, 20); math.Abs(y-1) > 1e-9 {
+			t.Errorf("loop %d: level = %v, want 1", i, y)
+		}
+	}
+	if e.Done() {
+		t.Error("done while looping")
+	}
+	e.Release()
+	if y := sing(&e, 30); math.Abs(y-1) > 1e-9 || e.Done() {
+		t.Errorf("level at end of released loop = %v (done %v), want 1", y, e.Done())
+	}
+	if y := sing(&e, 10); y != 0 || !e.Done() {
+		t.Errorf("final level = %v (done %v), want 0", y, e.Done())
+	}
+}
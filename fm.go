@@ -0,0 +1,187 @@
+package audio
+
+import "math"
+
+// FMSynth is an Instrument made of phase-modulating sine operators, in the style of the Yamaha DX synthesizers.
+type FMSynth struct {
+	Params    Params
+	Operators []FMOperator
+	Algorithm FMAlgorithm
+	voices    MultiVoice
+}
+
+// FMOperator describes one sine operator of an FMSynth.
+type FMOperator struct {
+	Ratio float64 // frequency relative to the note's frequency
+	Fixed float64 // fixed frequency in Hz; if nonzero, Ratio is ignored
+	// Level scales the operator's output.  For a modulator, it is the modulation index in radians.
+	Level float64
+	// Feedback is the index (in radians) with which the operator modulates itself.
+	Feedback float64
+	// Envelope is a sequence of exponential segments, played from the start of each note.  The last level
+	// is held until the note ends, after which the envelope falls to zero in Release seconds.
+	// An empty envelope is held at 1.
+	Envelope []EnvSegment
+	Release  float64
+}
+
+// EnvSegment is an envelope segment that goes to Level in Time seconds.
+type EnvSegment struct {
+	Level, Time float64
+}
+
+// FMAlgorithm routes the operators of an FMSynth.
+type FMAlgorithm struct {
+	// Mod[i][j] scales the modulation of operator i by operator j.  Operators are computed from last to first,
+	// so an operator modulating one with a lower index does so without delay; otherwise it is delayed by a sample.
+	Mod [][]float64
+	// Out[i] is the level at which operator i is heard.
+	Out []float64
+}
+
+// NewFMAlgorithm returns an algorithm for n operators with the given carriers (heard operators) and
+// modulations, each of which is a pair {modulator, carrier} of operator indices.
+func NewFMAlgorithm(n int, carriers []int, modulations ...[2]int) FMAlgorithm {
+	a := FMAlgorithm{Mod: make([][]float64, n), Out: make([]float64, n)}
+	for i := range a.Mod {
+		a.Mod[i] = make([]float64, n)
+	}
+	for _, c := range carriers {
+		a.Out[c] = 1
+	}
+	for _, m := range modulations {
+		a.Mod[m[1]][m[0]] = 1
+	}
+	return a
+}
+
+// FMAlgorithms are the eight classic four-operator algorithms.  Operator 3 is the one usually given feedback.
+var FMAlgorithms = [8]FMAlgorithm{
+	NewFMAlgorithm(4, []int{0}, [2]int{3, 2}, [2]int{2, 1}, [2]int{1, 0}),
+	NewFMAlgorithm(4, []int{0}, [2]int{3, 1}, [2]int{2, 1}, [2]int{1, 0}),
+	NewFMAlgorithm(4, []int{0}, [2]int{3, 0}, [2]int{2, 1}, [2]int{1, 0}),
+	NewFMAlgorithm(4, []int{0}, [2]int{3, 2}, [2]int{2, 0}, [2]int{1, 0}),
+	NewFMAlgorithm(4, []int{0, 2}, [2]int{3, 2}, [2]int{1, 0}),
+	NewFMAlgorithm(4, []int{0, 1, 2}, [2]int{3, 0}, [2]int{3, 1}, [2]int{3, 2}),
+	NewFMAlgorithm(4, []int{0, 1, 2}, [2]int{3, 2}),
+	NewFMAlgorithm(4, []int{0, 1, 2, 3}),
+}
+
+// FMNote is the note type of FMSynth.  Pitch is log2 of the frequency (see MIDINotePitch).  The note lasts
+// until the end of its Pitch and Amplitude controls, after which the operators' envelopes are released.
+type FMNote struct {
+	Pitch, Amplitude []*ControlPoint
+}
+
+func (s *FMSynth) InitAudio(p Params) {
+	s.Params = p
+	s.voices.Params = p
+	Init(s.voices.Voices, p)
+}
+
+func (s *FMSynth) Play(n FMNote) {
+	v := &fmVoice{
+		synth: s,
+		ops:   make([]fmOperator, len(s.Operators)),
+	}
+	v.pitch.points = n.Pitch
+	v.amp.points = n.Amplitude
+	s.voices.Add(v)
+}
+
+func (s *FMSynth) Sing() float64 {
+	return s.voices.Sing()
+}
+
+func (s *FMSynth) Done() bool {
+	return s.voices.Done()
+}
+
+func (s *FMSynth) Stop() {
+	s.voices.Stop()
+}
+
+type fmVoice struct {
+	synth      *FMSynth
+	dt         float64
+	pitch, amp Control
+	ops        []fmOperator
+	y          []float64
+	released   bool
+}
+
+type fmOperator struct {
+	phase  float64
+	y1, y2 float64
+	env    ExpEnv
+}
+
+func (v *fmVoice) InitAudio(p Params) {
+	v.dt = 1 / p.SampleRate
+	v.pitch.InitAudio(p)
+	v.amp.InitAudio(p)
+	v.y = make([]float64, len(v.ops))
+	for i := range v.ops {
+		o := &v.ops[i]
+		o.env.InitAudio(p)
+		env := v.synth.Operators[i].Envelope
+		if len(env) == 0 {
+			o.env.Go(1, 0)
+		}
+		for _, s := range env {
+			o.env.Go(s.Level, s.Time)
+		}
+	}
+}
+
+func (v *fmVoice) Sing() float64 {
+	s := v.synth
+	freq := math.Exp2(v.pitch.Sing())
+	amp := v.amp.Sing()
+	if !v.released && v.pitch.Done() && v.amp.Done() {
+		v.released = true
+		for i := range v.ops {
+			v.ops[i].env.ReleaseNow(s.Operators[i].Release)
+		}
+	}
+
+	out := 0.0
+	for i := len(v.ops) - 1; i >= 0; i-- {
+		op := &s.Operators[i]
+		o := &v.ops[i]
+		f := op.Fixed
+		if f == 0 {
+			f = op.Ratio * freq
+		}
+		o.phase += f * v.dt
+		o.phase -= math.Floor(o.phase)
+
+		mod := op.Feedback * (o.y1 + o.y2) / 2
+		if i < len(s.Algorithm.Mod) {
+			for j, m := range s.Algorithm.Mod[i] {
+				if m != 0 && j < len(v.y) {
+					mod += m * v.y[j]
+				}
+			}
+		}
+		y := op.Level * o.env.Sing() * math.Sin(2*math.Pi*o.phase+mod)
+		o.y2, o.y1 = o.y1, y
+		v.y[i] = y
+		if i < len(s.Algorithm.Out) {
+			out += s.Algorithm.Out[i] * y
+		}
+	}
+	return amp * out
+}
+
+func (v *fmVoice) Done() bool {
+	if !v.released {
+		return false
+	}
+	for i := range v.ops {
+		if i < len(v.synth.Algorithm.Out) && v.synth.Algorithm.Out[i] != 0 && !v.ops[i].env.Done() {
+			return false
+		}
+	}
+	return true
+}
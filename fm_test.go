@@ -0,0 +1,101 @@
+package audio
+
+import (
+	"math"
+	"math/cmplx"
+	"testing"
+)
+
+func TestFMSynth(t *testing.T) {
+	s := &FMSynth{
+		Operators: []FMOperator{
+			{Ratio: 1, Level: 1, Envelope: []EnvSegment{{1, .01}}, Release: .1},
+			{Ratio: 2, Level: 2, Envelope: []EnvSegment{{1, .01}, {.5, .2}}, Release: .1},
+			{Ratio: 3, Level: 1, Release: .1},
+			{Ratio: 1, Level: 1, Feedback: .5, Release: .1},
+		},
+		Algorithm: FMAlgorithms[0],
+	}
+	p := NewPatternPlayer(&Pattern{Notes: []*Note{{
+		Time: .1,
+		Attributes: map[string][]*ControlPoint{
+			"Pitch":     {{Time: 0, Value: MIDINotePitch(69)}, {Time: .5, Value: MIDINotePitch(69)}},
+			"Amplitude": {{Time: 0, Value: 1}, {Time: .5, Value: 1}},
+		},
+	}}}, s)
+	Init(p, Params{48000})
+
+	peak := 0.0
+	n := 0
+	for ; !p.Done() && n < 48000; n++ {
+		if x := p.Sing(); x > peak {
+			peak = x
+		}
+	}
+	if peak < .5 {
+		t.Errorf("expected a loud note, got peak %f", peak)
+	}
+	if n < 28800 || n >= 48000 {
+		t.Errorf("expected the note to end after about .7 seconds, got %f", float64(n)/48000)
+	}
+}
+
+// fmSpectrum plays a one second A440 on s and returns the amplitudes of its sine components at freqs, which
+// must be whole numbers of Hz.
+func fmSpectrum(s *FMSynth, freqs ...float64) []float64 {
+	const sampleRate = 48000
+	Init(s, Params{sampleRate})
+	s.Play(FMNote{
+		Pitch:     []*ControlPoint{{Time: 0, Value: math.Log2(440)}, {Time: 2, Value: math.Log2(440)}},
+		Amplitude: []*ControlPoint{{Time: 0, Value: 1}, {Time: 2, Value: 1}},
+	})
+	sums := make([]complex128, len(freqs))
+	for i := 1; i <= sampleRate; i++ {
+		y := s.Sing()
+		for j, f := range freqs {
+			sums[j] += complex(y, 0) * cmplx.Exp(complex(0, -2*math.Pi*f*float64(i)/sampleRate))
+		}
+	}
+	amps := make([]float64, len(freqs))
+	for j := range sums {
+		amps[j] = 2 * cmplx.Abs(sums[j]) / sampleRate
+	}
+	return amps
+}
+
+func TestFMSynthSpectrum(t *testing.T) {
+	// A carrier at 4 times the note frequency, modulated by the note frequency with index β, has sidebands
+	// at multiples of the note frequency with amplitudes given by Bessel functions of β.
+	const β = 1
+	s := &FMSynth{
+		Operators: []FMOperator{{Ratio: 4, Level: 1}, {Ratio: 1, Level: β}},
+		Algorithm: NewFMAlgorithm(2, []int{0}, [2]int{1, 0}),
+	}
+	amps := fmSpectrum(s, 440, 880, 1320, 1760, 2200, 2640, 3080)
+	for i, amp := range amps {
+		n := i - 3
+		if want := math.Abs(math.Jn(n, β)); math.Abs(amp-want) > .01 {
+			t.Errorf("sideband %d:  expected amplitude %.3f, got %.3f", n, want, amp)
+		}
+	}
+
+	// With no modulation, each carrier is a sine at its level.
+	s = &FMSynth{
+		Operators: []FMOperator{{Ratio: 1, Level: .4}, {Ratio: 2, Level: .3}, {Fixed: 1000, Level: .2}},
+		Algorithm: NewFMAlgorithm(3, []int{0, 1, 2}),
+	}
+	for i, amp := range fmSpectrum(s, 440, 880, 1000, 1320) {
+		if want := []float64{.4, .3, .2, 0}[i]; math.Abs(amp-want) > .001 {
+			t.Errorf("carrier %d:  expected amplitude %.3f, got %.3f", i, want, amp)
+		}
+	}
+
+	// Feedback adds harmonics.
+	s = &FMSynth{
+		Operators: []FMOperator{{Ratio: 1, Level: 1, Feedback: 1}},
+		Algorithm: NewFMAlgorithm(1, []int{0}),
+	}
+	if amps := fmSpectrum(s, 440, 880); amps[0] > .99 || amps[1] < .1 {
+		t.Errorf("feedback:  expected a second harmonic, got amplitudes %.3f and %.3f", amps[0], amps[1])
+	}
+}
@@ -0,0 +1,193 @@
+package audio
+
+import "math"
+
+// GrainWindow is the amplitude envelope of each grain of a Granulator.
+type GrainWindow int
+
+const (
+	HannGrain GrainWindow = iota
+	TriangleGrain
+	GaussianGrain
+	TrapezoidGrain // rises and falls over a quarter of the grain each
+)
+
+func (w GrainWindow) at(x float64) float64 {
+	switch w {
+	case TriangleGrain:
+		return 1 - math.Abs(2*x-1)
+	case GaussianGrain:
+		d := (x - .5) / .15
+		return math.Exp(-d * d / 2)
+	case TrapezoidGrain:
+		return math.Min(1, 4*math.Min(x, 1-x))
+	}
+	return (1 - math.Cos(2*math.Pi*x)) / 2
+}
+
+// Granulator is a StereoVoice that plays a recording as a stream of short, overlapping grains.  Grains are
+// read from around a playhead that moves through the recording at the Scan rate, so a long recording can be
+// played time-compressed (or frozen, or stretched) independently of its pitch.
+type Granulator struct {
+	// Position is an offset in seconds from the playhead at which grains are read.
+	Position Control
+	// Scan is the rate at which the playhead moves; 1 is the recording's own speed and 0 freezes it.
+	Scan Control
+	// Size is the duration of grains in seconds (default .05 if zero).
+	Size Control
+	// Density is the number of grains started per second (default 20 if zero).
+	Density Control
+	// Pitch is the transposition of grains in octaves.
+	Pitch Control
+	// Jitter (0..1) randomizes the start time of each grain, by up to a grain interval, and its read
+	// position, by up to a grain size.
+	Jitter Control
+	// PitchJitter is the maximum random transposition of each grain in octaves.
+	PitchJitter Control
+	// Spread (0..1) pans each grain randomly up to this far to either side.
+	Spread Control
+
+	Window GrainWindow
+	// If Sync is true, grains are scheduled at regular intervals; otherwise, at random (Poisson) times.
+	Sync bool
+	// If Loop is true, reading wraps around the ends of the recording.  Otherwise, the Granulator is done
+	// when the playhead passes the end.
+	Loop bool
+
+	buf    []float64
+	bufSR  float64
+	dt     float64
+	rand   FastRand
+	head   float64 // playhead, in seconds
+	next   float64 // samples until the next grain
+	grains []grain
+}
+
+type grain struct {
+	wait       int     // samples before the grain starts
+	pos, step  float64 // read position and increment, in buffer samples
+	i, n       int
+	gainL      float64
+	gainR      float64
+	normalized float64
+}
+
+// NewGranulator returns a Granulator reading from buf, which is sampled at sampleRate.
+func NewGranulator(buf []float64, sampleRate float64) *Granulator {
+	return &Granulator{buf: buf, bufSR: sampleRate}
+}
+
+// Seed sets the seed of the Granulator's randomness.  Unless seeded, the Granulator is seeded by InitAudio
+// (see NewSeed).
+func (g *Granulator) Seed(seed int64) {
+	g.rand.Seed(seed)
+}
+
+func (g *Granulator) InitAudio(p Params) {
+	g.dt = 1 / p.SampleRate
+	g.rand.seedIfZero()
+	for _, c := range []*Control{&g.Position, &g.Scan, &g.Size, &g.Density, &g.Pitch, &g.Jitter, &g.PitchJitter, &g.Spread} {
+		c.InitAudio(p)
+	}
+}
+
+// SetTime sets the playhead, in seconds of the recording, and clears all grains.
+func (g *Granulator) SetTime(t float64) {
+	g.head = t
+	g.next = 0
+	g.grains = g.grains[:0]
+}
+
+func (g *Granulator) Sing() (float64, float64) {
+	pos := g.head + g.Position.Sing()
+	scan := g.Scan.Sing()
+	size := g.Size.Sing()
+	if size <= 0 {
+		size = .05
+	}
+	density := g.Density.Sing()
+	if density <= 0 {
+		density = 20
+	}
+	pitch := g.Pitch.Sing()
+	jitter := g.Jitter.Sing()
+	pitchJitter := g.PitchJitter.Sing()
+	spread := g.Spread.Sing()
+
+	end := float64(len(g.buf)) / g.bufSR
+	if g.Loop || pos < end {
+		for g.next--; g.next <= 0; {
+			interval := math.Max(1, 1/(density*g.dt))
+			wait := 0.0
+			if g.Sync {
+				wait = jitter * g.rand.Float64() * interval
+				g.next += interval
+			} else {
+				g.next += -math.Log(1-g.rand.Float64()) * interval
+			}
+			overlap := math.Max(1, size*density)
+			pan := (1 + spread*g.rand.Bipolar()) * math.Pi / 4
+			g.grains = append(g.grains, grain{
+				wait:       int(wait),
+				pos:        (pos + jitter*size*g.rand.Bipolar()) * g.bufSR,
+				step:       g.bufSR * g.dt * math.Exp2(pitch+pitchJitter*g.rand.Bipolar()),
+				n:          int(math.Max(1, size/g.dt)),
+				gainL:      math.Cos(pan),
+				gainR:      math.Sin(pan),
+				normalized: 1 / math.Sqrt(overlap),
+			})
+		}
+	}
+	g.head += scan * g.dt
+
+	l, r := 0.0, 0.0
+	for i := 0; i < len(g.grains); {
+		gr := &g.grains[i]
+		if gr.wait > 0 {
+			gr.wait--
+			i++
+			continue
+		}
+		x := gr.normalized * g.Window.at(float64(gr.i)/float64(gr.n)) * g.read(gr.pos)
+		l += gr.gainL * x
+		r += gr.gainR * x
+		gr.pos += gr.step
+		gr.i++
+		if gr.i >= gr.n {
+			last := len(g.grains) - 1
+			g.grains[i] = g.grains[last]
+			g.grains = g.grains[:last]
+			continue
+		}
+		i++
+	}
+	return l, r
+}
+
+// read returns the recording at a fractional sample index.
+func (g *Granulator) read(pos float64) float64 {
+	n := len(g.buf)
+	if n == 0 {
+		return 0
+	}
+	at := func(i int) float64 {
+		if g.Loop {
+			return g.buf[(i%n+n)%n]
+		}
+		if i < 0 || i >= n {
+			return 0
+		}
+		return g.buf[i]
+	}
+	j, f := math.Modf(pos)
+	i := int(j)
+	if f < 0 {
+		i--
+		f++
+	}
+	return Interp3(f, at(i-1), at(i), at(i+1), at(i+2))
+}
+
+func (g *Granulator) Done() bool {
+	return !g.Loop && g.head+g.Position.x >= float64(len(g.buf))/g.bufSR && len(g.grains) == 0
+}
@@ -0,0 +1,54 @@
+package audio
+
+import (
+	"math"
+	"testing"
+)
+
+func TestGranulator(t *testing.T) {
+	const sampleRate = 48000
+	buf := make([]float64, 2*sampleRate)
+	for i := range buf {
+		buf[i] = math.Sin(2 * math.Pi * 1000 * float64(i) / sampleRate)
+	}
+	for _, sync := range []bool{true, false} {
+		g := NewGranulator(buf, sampleRate)
+		g.Seed(1)
+		g.Sync = sync
+		Init(g, Params{sampleRate})
+		g.Scan.SetPoints([]*ControlPoint{{Time: 0, Value: 4}})
+		g.Pitch.SetPoints([]*ControlPoint{{Time: 0, Value: 1}})
+		g.Density.SetPoints([]*ControlPoint{{Time: 0, Value: 50}})
+		g.Spread.SetPoints([]*ControlPoint{{Time: 0, Value: 1}})
+
+		var x []float64
+		for !g.Done() && len(x) < 2*sampleRate {
+			l, r := g.Sing()
+			x = append(x, l+r)
+		}
+		if d := float64(len(x)) / sampleRate; d < .5 || d > .6 {
+			t.Errorf("sync=%v: expected 2 seconds scanned at 4x to last about .5 seconds, got %f", sync, d)
+		}
+		if f := estimateFreq(x[sampleRate/10:sampleRate/2], sampleRate, 2000); math.Abs(f/2000-1) > .01 {
+			t.Errorf("sync=%v: expected frequency 2000, got %f", sync, f)
+		}
+	}
+}
+
+func TestGranulatorTinyGrains(t *testing.T) {
+	const sampleRate = 48000
+	buf := make([]float64, sampleRate)
+	for i := range buf {
+		buf[i] = 1
+	}
+	g := NewGranulator(buf, sampleRate)
+	g.Seed(1)
+	Init(g, Params{sampleRate})
+	g.Size.SetPoints([]*ControlPoint{{Time: 0, Value: .1 / sampleRate}})
+	g.Density.SetPoints([]*ControlPoint{{Time: 0, Value: 1000}})
+	for i := 0; i < sampleRate/10; i++ {
+		if l, r := g.Sing(); math.IsNaN(l) || math.IsNaN(r) {
+			t.Fatalf("sample %d: NaN output for grains shorter than a sample", i)
+		}
+	}
+}
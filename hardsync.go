@@ -0,0 +1,45 @@
+package audio
+
+// A SyncMaster is an oscillator that can drive hard sync.
+type SyncMaster interface {
+	Sing() float64
+	// Wrapped reports whether the oscillator's phase wrapped around during the last sample and, if so, how
+	// long (in samples, 0..1) before the last sample it did so.
+	Wrapped() (ago float64, ok bool)
+}
+
+// A SyncSlave is an oscillator that can be hard-synced.
+type SyncSlave interface {
+	Sing() float64
+	// Sync resets the phase to zero ago samples (0..1) before the last sample.  It returns the last sample
+	// recomputed accordingly and the size of the discontinuity at the reset.
+	Sync(ago float64) (y, jump float64)
+}
+
+// HardSync resets the phase of Slave whenever the phase of Master wraps around, which gives the slave the
+// master's period.  The discontinuities caused by resets are smoothed with PolyBLEP residuals, for which
+// the output is delayed by one sample.
+type HardSync struct {
+	Master SyncMaster
+	Slave  SyncSlave
+	y      float64
+}
+
+func NewHardSync(master SyncMaster, slave SyncSlave) *HardSync {
+	return &HardSync{Master: master, Slave: slave}
+}
+
+func (s *HardSync) Sing() float64 {
+	s.Master.Sing()
+	y := s.Slave.Sing()
+	out := s.y
+	if ago, ok := s.Master.Wrapped(); ok {
+		var jump float64
+		y, jump = s.Slave.Sync(ago)
+		// The step occurred ago samples before y and 1-ago samples after the previous sample.
+		out += jump / 2 * ago * ago
+		y -= jump / 2 * (1 - ago) * (1 - ago)
+	}
+	s.y = y
+	return out
+}
@@ -0,0 +1,145 @@
+package audio
+
+import (
+	"math"
+	"testing"
+)
+
+func TestReset(t *testing.T) {
+	p := Params{48000}
+	sine := &SineOsc{}
+	pm := &SinePM{}
+	selfPM := &SineSelfPM{}
+	saw := &SawOsc{}
+	blsaw := &BLSawOsc{}
+	Init(sine, p)
+	Init(pm, p)
+	Init(selfPM, p)
+	Init(saw, p)
+	Init(blsaw, p)
+	sine.Freq(440)
+	pm.Freq(440)
+	selfPM.Freq(440)
+	saw.Freq(440)
+	blsaw.Freq(440)
+	for i := 0; i < 1000; i++ {
+		sine.Sing()
+		pm.Sing()
+		selfPM.Sing()
+		saw.Sing()
+		blsaw.Sing()
+	}
+	for _, c := range []struct {
+		name string
+		y    float64
+		want float64
+	}{
+		{"SineOsc", sine.Reset(.25).Sing(), 1},
+		{"SinePM", pm.Reset(.25).Sing(), 1},
+		{"SineSelfPM", selfPM.Reset(.75).Sing(), -1},
+		{"SawOsc", saw.Reset(.75).Sing(), .5},
+		{"BLSawOsc", blsaw.Reset(.5).Sing(), 0},
+	} {
+		if math.Abs(c.y-c.want) > 1e-9 {
+			t.Errorf("%s: expected %v, got %v", c.name, c.want, c.y)
+		}
+	}
+}
+
+func TestHardSync(t *testing.T) {
+	const period = 480
+	p := Params{48000}
+	for _, slave := range []SyncSlave{&SawOsc{}, &BLSawOsc{}, &SineOsc{}, &SinePM{}, &SineSelfPM{}} {
+		master := &SawOsc{}
+		Init(master, p)
+		Init(slave, p)
+		master.Freq(p.SampleRate / period)
+		switch s := slave.(type) {
+		case *SawOsc:
+			s.Freq(317)
+		case *BLSawOsc:
+			s.Freq(317)
+		case *SineOsc:
+			s.Freq(317)
+		case *SinePM:
+			s.Freq(317)
+		case *SineSelfPM:
+			s.Freq(317).Index(.5)
+		}
+		sync := NewHardSync(master, slave)
+		y := make([]float64, 4*period)
+		for i := range y {
+			y[i] = sync.Sing()
+		}
+		for i := 2 * period; i < len(y); i++ {
+			if d := math.Abs(y[i] - y[i-period]); d > 1e-6 {
+				t.Fatalf("%T: output not periodic at sample %d (difference %g)", slave, i, d)
+			}
+		}
+	}
+}
+
+func TestHardSyncAliasing(t *testing.T) {
+	// The ratios are chosen so that the resets cause large jumps.  What aliasing remains with the correction
+	// is mostly the slave's own (see TestBandLimitedOscs).
+	for _, ratio := range []float64{1.7, 2.43, 2.9} {
+		naive := aliasingEnergy(&syncOsc{ratio: ratio, naive: true})
+		blep := aliasingEnergy(&syncOsc{ratio: ratio})
+		t.Logf("ratio %v: aliasing %.1f dB without correction, %.1f dB with", ratio, naive, blep)
+		if blep > naive-6 {
+			t.Errorf("ratio %v: expected the PolyBLEP correction to reduce aliasing by at least 6 dB, got %.1f dB vs %.1f dB", ratio, blep, naive)
+		}
+	}
+
+	// The master may be any SyncMaster.
+	master := &SineSelfPM{}
+	slave := &SawOsc{}
+	Init(master, Params{48000})
+	Init(slave, Params{48000})
+	master.Freq(100).Index(.3)
+	slave.Freq(270)
+	sync := NewHardSync(master, slave)
+	resets := 0
+	for i := 0; i < 48000; i++ {
+		sync.Sing()
+		if _, ok := master.Wrapped(); ok {
+			resets++
+		}
+	}
+	if resets < 99 || resets > 101 {
+		t.Errorf("expected 100 resets from a 100 Hz SineSelfPM master, got %d", resets)
+	}
+}
+
+// syncOsc is a band-limited sawtooth hard-synced to a master sawtooth ratio times slower, with or without the
+// PolyBLEP correction of HardSync.
+type syncOsc struct {
+	ratio  float64
+	naive  bool
+	master SawOsc
+	slave  BLSawOsc
+	sync   *HardSync
+}
+
+func (s *syncOsc) InitAudio(p Params) {
+	Init(&s.master, p)
+	Init(&s.slave, p)
+	s.sync = NewHardSync(&s.master, &s.slave)
+}
+
+func (s *syncOsc) Freq(freq float64) {
+	s.master.Freq(freq)
+	s.slave.Freq(freq * s.ratio)
+}
+
+func (s *syncOsc) Sing() float64 {
+	if !s.naive {
+		return s.sync.Sing()
+	}
+	s.master.Sing()
+	y := s.slave.Sing()
+	if ago, ok := s.master.Wrapped(); ok {
+		y, _ = s.slave.Sync(ago)
+	}
+	return y
+}
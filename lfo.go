@@ -0,0 +1,135 @@
+package audio
+
+import "math"
+
+// LFOShape is the waveform of an LFO.
+type LFOShape int
+
+const (
+	SineLFO LFOShape = iota
+	TriangleLFO
+	SawLFO
+	SquareLFO
+	SampleHoldLFO   // a new random value each cycle
+	SmoothRandomLFO // a smooth curve through a new random value each cycle, like SlowRand
+)
+
+// LFO is a low-frequency oscillator for modulating other units, e.g. frequencies, filter cutoffs or pans.
+// Its output is in -1..1, or 0..1 if Unipolar.  All shapes start at their midpoint (zero, when bipolar)
+// and, except the square, rise from there.
+//
+// The rate is set either in Hz (Freq) or in beats at a tempo (Sync and Tempo).
+type LFO struct {
+	Shape LFOShape
+	// Offset is added to the phase (0..1).
+	Offset   float64
+	Unipolar bool
+
+	dt    float64
+	freq  float64
+	beats float64 // the length of a cycle in beats, if synced
+	tempo float64 // beats per minute
+	p     float64
+	q     float64 // previous phase including offset, to detect a new cycle
+	rand  FastRand
+	x     [4]float64
+}
+
+func NewLFO(shape LFOShape, freq float64) *LFO {
+	return &LFO{Shape: shape, freq: freq}
+}
+
+func (l *LFO) InitAudio(p Params) {
+	l.dt = 1 / p.SampleRate
+	l.rand.seedIfZero()
+	for i := range l.x {
+		l.x[i] = l.random()
+	}
+	l.q = l.phase()
+}
+
+// Freq sets the rate in Hz, ending tempo sync.
+func (l *LFO) Freq(freq float64) *LFO {
+	l.freq = freq
+	l.beats = 0
+	return l
+}
+
+// Sync sets the length of a cycle in beats, so that the rate follows the tempo.
+func (l *LFO) Sync(beats float64) *LFO {
+	l.beats = beats
+	return l
+}
+
+// Tempo sets the tempo in beats per minute, which determines the rate when synced.
+func (l *LFO) Tempo(bpm float64) *LFO {
+	l.tempo = bpm
+	return l
+}
+
+// Reset restarts the cycle (before Offset), so that the next output is at the start.
+func (l *LFO) Reset() *LFO {
+	l.p = 0
+	l.q = l.phase()
+	return l
+}
+
+// Seed sets the seed of the random shapes.  Unless seeded, the LFO is seeded by InitAudio (see NewSeed).
+func (l *LFO) Seed(seed int64) *LFO {
+	l.rand.Seed(seed)
+	return l
+}
+
+func (l *LFO) rate() float64 {
+	if l.beats > 0 {
+		return l.tempo / 60 / l.beats
+	}
+	return l.freq
+}
+
+func (l *LFO) phase() float64 {
+	q := l.p + l.Offset
+	return q - math.Floor(q)
+}
+
+func (l *LFO) random() float64 {
+	return .8 * l.rand.Bipolar() // .8 keeps the interpolated curve within -1..1
+}
+
+func (l *LFO) Sing() float64 {
+	q := l.phase()
+	if q < l.q {
+		copy(l.x[:], l.x[1:])
+		l.x[3] = l.random()
+	}
+	l.q = q
+	l.p += l.rate() * l.dt
+	l.p -= math.Floor(l.p)
+
+	var y float64
+	switch l.Shape {
+	case SineLFO:
+		y = math.Sin(2 * math.Pi * q)
+	case TriangleLFO:
+		q += .25
+		y = 1 - 4*math.Abs(q-math.Floor(q)-.5)
+	case SawLFO:
+		q += .5
+		y = 2*(q-math.Floor(q)) - 1
+	case SquareLFO:
+		y = 1
+		if q >= .5 {
+			y = -1
+		}
+	case SampleHoldLFO:
+		y = l.x[2] / .8
+	case SmoothRandomLFO:
+		y = Interp3(q, l.x[0], l.x[1], l.x[2], l.x[3])
+	}
+	if l.Unipolar {
+		y = (y + 1) / 2
+	}
+	return y
+}
+
+func (l *LFO) Done() bool { return false }
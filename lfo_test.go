@@ -0,0 +1,53 @@
+package audio
+
+import (
+	"math"
+	"testing"
+)
+
+func TestLFO(t *testing.T) {
+	const sampleRate = 1000
+	for _, shape := range []LFOShape{SineLFO, TriangleLFO, SawLFO, SquareLFO, SampleHoldLFO, SmoothRandomLFO} {
+		// 120 bpm, a cycle every 2 beats:  1 Hz
+		l := NewLFO(shape, 0).Sync(2).Tempo(120).Seed(1)
+		l.Unipolar = true
+		Init(l, Params{sampleRate})
+		x := make([]float64, 4*sampleRate)
+		for i := range x {
+			x[i] = l.Sing()
+			if x[i] < 0 || x[i] > 1 {
+				t.Fatalf("shape %d: output %f out of range", shape, x[i])
+			}
+		}
+		if shape < SampleHoldLFO {
+			if math.Abs(x[0]-.5) > 1e-9 && shape != SquareLFO {
+				t.Errorf("shape %d: expected to start at .5, got %f", shape, x[0])
+			}
+			for i := sampleRate; i < len(x); i++ {
+				if math.Abs(x[i]-x[i-sampleRate]) > 1e-6 {
+					t.Fatalf("shape %d: expected a period of one second", shape)
+				}
+			}
+		}
+		if shape == SampleHoldLFO {
+			for i := 1; i < len(x); i++ {
+				if i%sampleRate != 0 && x[i] != x[i-1] {
+					t.Fatalf("sample and hold changed within a cycle at %d", i)
+				}
+			}
+		}
+	}
+
+	a, b := NewLFO(SmoothRandomLFO, 5).Seed(7), NewLFO(SmoothRandomLFO, 5).Seed(7)
+	a.Offset = .5
+	Init(a, Params{sampleRate})
+	Init(b, Params{sampleRate})
+	for i := 0; i < 100; i++ {
+		b.Sing()
+	}
+	for i := 0; i < 1000; i++ {
+		if x, y := a.Sing(), b.Sing(); math.Abs(x-y) > 1e-9 {
+			t.Fatalf("expected an offset of half a cycle to advance the same random curve by 100 samples")
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package audio
+
+import (
+	"math"
+	"math/cmplx"
+)
+
+// FastRand is a small, fast, seedable pseudo-random number generator (xorshift64*) suitable for audio-rate noise.
+// The zero value is seeded from NewSeed when first used.
+type FastRand struct {
+	s uint64
+}
+
+func NewFastRand(seed int64) *FastRand {
+	r := &FastRand{}
+	r.Seed(seed)
+	return r
+}
+
+func (r *FastRand) Seed(seed int64) {
+	// splitmix64 spreads similar seeds over the state space
+	z := uint64(seed) + 0x9E3779B97F4A7C15
+	z = (z ^ z>>30) * 0xBF58476D1CE4E5B9
+	z = (z ^ z>>27) * 0x94D049BB133111EB
+	z ^= z >> 31
+	if z == 0 {
+		z = 1
+	}
+	r.s = z
+}
+
+// seedIfZero seeds an unseeded FastRand from NewSeed.
+func (r *FastRand) seedIfZero() {
+	if r.s == 0 {
+		r.Seed(NewSeed())
+	}
+}
+
+func (r *FastRand) Uint64() uint64 {
+	r.seedIfZero() // xorshift never returns to 0, so only an unseeded state is 0
+	r.s ^= r.s >> 12
+	r.s ^= r.s << 25
+	r.s ^= r.s >> 27
+	return r.s * 2685821657736338717
+}
+
+// Float64 returns a number in 0..1.
+func (r *FastRand) Float64() float64 {
+	return float64(r.Uint64()>>11) / (1 << 53)
+}
+
+// Bipolar returns a number in -1..1.
+func (r *FastRand) Bipolar() float64 {
+	return 2*r.Float64() - 1
+}
+
+// WhiteNoise is uniformly distributed noise in -1..1 with a flat spectrum.
+// The zero value is seeded by InitAudio (see NewSeed).
+type WhiteNoise struct {
+	rand FastRand
+}
+
+func NewWhiteNoise(seed int64) *WhiteNoise {
+	n := &WhiteNoise{}
+	n.rand.Seed(seed)
+	return n
+}
+
+func (n *WhiteNoise) InitAudio(p Params) { n.rand.seedIfZero() }
+func (n *WhiteNoise) Sing() float64      { return n.rand.Bipolar() }
+func (n *WhiteNoise) Done() bool         { return false }
+
+// PinkNoise has a spectrum falling at 3 dB per octave.
+type PinkNoise struct {
+	white  WhiteNoise
+	filter slopeFilter
+}
+
+func NewPinkNoise(seed int64) *PinkNoise {
+	n := &PinkNoise{}
+	n.white.rand.Seed(seed)
+	return n
+}
+
+func (n *PinkNoise) InitAudio(p Params) {
+	n.white.InitAudio(p)
+	n.filter.init(p, false)
+}
+
+func (n *PinkNoise) Sing() float64 { return n.filter.filter(n.white.Sing()) }
+func (n *PinkNoise) Done() bool    { return false }
+
+// BlueNoise has a spectrum rising at 3 dB per octave.
+type BlueNoise struct {
+	white  WhiteNoise
+	filter slopeFilter
+}
+
+func NewBlueNoise(seed int64) *BlueNoise {
+	n := &BlueNoise{}
+	n.white.rand.Seed(seed)
+	return n
+}
+
+func (n *BlueNoise) InitAudio(p Params) {
+	n.white.InitAudio(p)
+	n.filter.init(p, true)
+}
+
+func (n *BlueNoise) Sing() float64 { return n.filter.filter(n.white.Sing()) }
+func (n *BlueNoise) Done() bool    { return false }
+
+// BrownNoise (or red noise) has a spectrum falling at 6 dB per octave.  It is integrated white noise, with a
+// slight leak below 10 Hz to keep it from wandering off.
+type BrownNoise struct {
+	white   WhiteNoise
+	a, g, y float64
+}
+
+func NewBrownNoise(seed int64) *BrownNoise {
+	n := &BrownNoise{}
+	n.white.rand.Seed(seed)
+	return n
+}
+
+func (n *BrownNoise) InitAudio(p Params) {
+	n.white.InitAudio(p)
+	n.a = math.Exp(-2 * math.Pi * 10 / p.SampleRate)
+	// unity gain at 1 kHz
+	n.g = cmplx.Abs(1 - complex(n.a, 0)*cmplx.Exp(complex(0, -2*math.Pi*noiseRefFreq/p.SampleRate)))
+}
+
+func (n *BrownNoise) Sing() float64 {
+	n.y = n.a*n.y + n.g*n.white.Sing()
+	return n.y
+}
+
+func (n *BrownNoise) Done() bool { return false }
+
+// VelvetNoise is a sparse sequence of impulses of random sign, one placed randomly in each period of
+// 1/density seconds.  It sounds smoother than white noise and is cheap to convolve with.
+type VelvetNoise struct {
+	rand    FastRand
+	density float64
+	period  float64
+	k       int64 // index of the current period
+	i, end  int64 // current sample, end of the current period
+	next    int64 // sample of the next impulse
+	sign    float64
+}
+
+func NewVelvetNoise(density float64, seed int64) *VelvetNoise {
+	n := &VelvetNoise{density: density}
+	n.rand.Seed(seed)
+	return n
+}
+
+func (n *VelvetNoise) InitAudio(p Params) {
+	n.rand.seedIfZero()
+	n.period = p.SampleRate / n.density
+	n.k, n.i, n.end = 0, 0, 0
+}
+
+func (n *VelvetNoise) Sing() float64 {
+	if n.i >= n.end {
+		n.k++
+		begin := n.end
+		n.end = int64(float64(n.k) * n.period)
+		n.next = begin + int64(n.rand.Float64()*float64(n.end-begin))
+		n.sign = 1
+		if n.rand.Uint64()&1 == 0 {
+			n.sign = -1
+		}
+	}
+	y := 0.0
+	if n.i == n.next {
+		y = n.sign
+	}
+	n.i++
+	return y
+}
+
+func (n *VelvetNoise) Done() bool { return false }
+
+// noiseRefFreq is the frequency at which the colored noises have the same spectral density as white noise.
+const noiseRefFreq = 1000
+
+// slopeFilter approximates a slope of -3 dB per octave (or +3 if inverted) from 10 Hz up to the Nyquist
+// frequency using first-order sections whose poles and zeros alternate every octave.  Poles and zeros are
+// placed in Hz, so the slope does not depend on the sample rate.
+type slopeFilter struct {
+	sections []poleZero
+	gain     float64
+}
+
+type poleZero struct {
+	p, z   float64
+	x1, y1 float64
+}
+
+func (f *slopeFilter) init(p Params, invert bool) {
+	f.sections = f.sections[:0]
+	nyquist := p.SampleRate / 2
+	for f0 := 10.0; f0 < nyquist; f0 *= 4 {
+		pole, zero := f0, 2*f0
+		if invert {
+			pole, zero = zero, pole
+		}
+		f.sections = append(f.sections, poleZero{
+			p: math.Exp(-2 * math.Pi * pole / p.SampleRate),
+			z: math.Exp(-2 * math.Pi * zero / p.SampleRate),
+		})
+	}
+	z := cmplx.Exp(complex(0, -2*math.Pi*noiseRefFreq/p.SampleRate))
+	h := complex(1, 0)
+	for _, s := range f.sections {
+		h *= (1 - complex(s.z, 0)*z) / (1 - complex(s.p, 0)*z)
+	}
+	f.gain = 1 / cmplx.Abs(h)
+}
+
+func (f *slopeFilter) filter(x float64) float64 {
+	x *= f.gain
+	for i := range f.sections {
+		s := &f.sections[i]
+		y := x - s.z*s.x1 + s.p*s.y1
+		s.x1, s.y1 = x, y
+		x = y
+	}
+	return x
+}
@@ -0,0 +1,100 @@
+package audio
+
+import (
+	"math"
+	"math/cmplx"
+	"testing"
+)
+
+func TestNoiseSlopes(t *testing.T) {
+	for _, sampleRate := range []float64{44100, 96000} {
+		for _, test := range []struct {
+			name  string
+			noise Voice
+			slope float64 // dB per octave
+		}{
+			{"white", NewWhiteNoise(1), 0},
+			{"pink", NewPinkNoise(1), -3},
+			{"blue", NewBlueNoise(1), 3},
+			{"brown", NewBrownNoise(1), -6},
+			{"velvet", NewVelvetNoise(2000, 1), 0},
+		} {
+			Init(test.noise, Params{sampleRate})
+			slope := spectralSlope(test.noise, sampleRate)
+			if math.Abs(slope-test.slope) > .5 {
+				t.Errorf("%s noise at %.f Hz: expected slope %.1f dB/octave, got %.2f", test.name, sampleRate, test.slope, slope)
+			}
+		}
+	}
+}
+
+func TestFastRandZero(t *testing.T) {
+	var r FastRand
+	x := r.Uint64()
+	for i := 0; i < 100; i++ {
+		if r.Uint64() != x {
+			return
+		}
+	}
+	t.Errorf("zero FastRand always returns %d", x)
+}
+
+func TestNoiseSeed(t *testing.T) {
+	a, b := NewPinkNoise(42), NewPinkNoise(42)
+	Init(a, Params{48000})
+	Init(b, Params{48000})
+	for i := 0; i < 1000; i++ {
+		if x, y := a.Sing(), b.Sing(); x != y {
+			t.Fatalf("sample %d: %f != %f", i, x, y)
+		}
+	}
+}
+
+// spectralSlope returns the least-squares slope (in dB per octave) of the power spectral density of v,
+// averaged over octave bands from 62.5 Hz to 8 kHz.
+func spectralSlope(v Voice, sampleRate float64) float64 {
+	const (
+		size     = 4096
+		segments = 64
+	)
+	window := make([]float64, size)
+	for i := range window {
+		window[i] = (1 - math.Cos(2*math.Pi*float64(i)/size)) / 2
+	}
+	f := NewFFT(size, nil).fft
+	power := make([]float64, size/2)
+	x := make([]complex128, size)
+	for s := 0; s < segments; s++ {
+		for i := range x {
+			x[i] = complex(window[i]*v.Sing(), 0)
+		}
+		for i, x := range f.Transform(x)[:size/2] {
+			power[i] += math.Pow(cmplx.Abs(x), 2)
+		}
+	}
+
+	var octaves, levels []float64
+	for lo := 62.5; lo < 8000; lo *= 2 {
+		sum, n := 0.0, 0
+		for i := int(lo * size / sampleRate); i < int(2*lo*size/sampleRate); i++ {
+			sum += power[i]
+			n++
+		}
+		octaves = append(octaves, math.Log2(lo))
+		levels = append(levels, 10*math.Log10(sum/float64(n)))
+	}
+
+	mx, my := 0.0, 0.0
+	for i := range octaves {
+		mx += octaves[i]
+		my += levels[i]
+	}
+	mx /= float64(len(octaves))
+	my /= float64(len(octaves))
+	sxy, sxx := 0.0, 0.0
+	for i := range octaves {
+		sxy += (octaves[i] - mx) * (levels[i] - my)
+		sxx += (octaves[i] - mx) * (octaves[i] - mx)
+	}
+	return sxy / sxx
+}
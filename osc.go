@@ -6,9 +6,11 @@ import (
 )
 
 type SineOsc struct {
-	pidt float64
-	freq float64
-	x, d complex128
+	pidt  float64
+	freq  float64
+	exact bool
+	x, d  complex128
+	n     int
 }
 
 func (o *SineOsc) InitAudio(p Params) {
@@ -21,12 +23,24 @@ func (o *SineOsc) InitAudio(p Params) {
 
 func (o *SineOsc) Freq(freq float64) *SineOsc {
 	o.freq = freq
-	o.d = o.expwdt_approx(freq)
+	if o.exact {
+		o.d = expwdt(freq * o.pidt)
+	} else {
+		o.d = o.expwdt_approx(freq)
+	}
 	return o
 }
 
+// Exact sets whether Freq computes the rotation accurately (see expwdt) rather than approximately (see
+// expwdt_approx).  Exact frequencies cost a few more operations per call to Freq.
+func (o *SineOsc) Exact(exact bool) *SineOsc {
+	o.exact = exact
+	return o.Freq(o.freq)
+}
+
 // expwdt_approx approximates cmplx.Exp(complex(0, w*dt)) as complex(1, w*dt/2) / complex(1, -w*dt/2), where w=2*pi*freq and dt=1/sampleRate.
 // At 96000 samples per second, it results in sine waves with frequencies >99% accurate up to 2048 Hz.  At higher frequences, the errors are typically imperceptible.  See TestSineOsc_expwdt_approx for details.
+// Use Exact for accurate frequencies.
 func (o *SineOsc) expwdt_approx(freq float64) complex128 {
 	wdt_2 := freq * o.pidt
 
@@ -36,16 +50,77 @@ func (o *SineOsc) expwdt_approx(freq float64) complex128 {
 	return complex((1-wdt_22)*_1wdt_22, 2*wdt_2*_1wdt_22)
 }
 
+// FM modulates the frequency set by Freq by adding hz, which may be large enough to make the instantaneous
+// frequency negative (through-zero FM).  It is cheap enough to call every sample.  The modulation lasts until
+// the next call to FM, ExpFM or Freq.  Unlike Freq, FM computes the rotation accurately (see expwdt); FM(0)
+// can be used to play the unmodulated frequency accurately.
+func (o *SineOsc) FM(hz float64) *SineOsc {
+	o.d = expwdt((o.freq + hz) * o.pidt)
+	return o
+}
+
+// ExpFM modulates the frequency set by Freq exponentially, by the given number of octaves.
+func (o *SineOsc) ExpFM(octaves float64) *SineOsc {
+	return o.FM(o.freq * (math.Exp2(octaves) - 1))
+}
+
+// expwdt computes cmplx.Exp(complex(0, 2*wdt_2)) as complex(1, w) / complex(1, -w), where w is a Padé
+// approximant of tan(wdt_2).  The resulting frequency error is below 0.001% up to the Nyquist frequency.
+func expwdt(wdt_2 float64) complex128 {
+	x2 := wdt_2 * wdt_2
+	w := wdt_2 * (945 - 105*x2 + x2*x2) / (945 - 420*x2 + 15*x2*x2)
+	w2 := w * w
+	_1w2 := 1 / (1 + w2)
+	return complex((1-w2)*_1w2, 2*w*_1w2)
+}
+
 func (o *SineOsc) Phase(phase float64) *SineOsc {
 	o.x = cmplx.Exp(complex(0, 2*math.Pi*phase))
 	return o
 }
 
+// Reset sets the phase (0..1) of the next sample, for deterministic retriggering.
+func (o *SineOsc) Reset(phase float64) *SineOsc {
+	o.x = cmplx.Exp(complex(0, 2*math.Pi*phase)) * cmplx.Conj(o.d)
+	return o
+}
+
+// Wrapped implements SyncMaster.  The phase wraps where the sine crosses zero upwards.
+func (o *SineOsc) Wrapped() (float64, bool) {
+	if imag(o.x) < 0 || real(o.x) <= 0 || imag(o.x*cmplx.Conj(o.d)) >= 0 {
+		return 0, false
+	}
+	return cmplx.Phase(o.x) / cmplx.Phase(o.d), true
+}
+
+// Sync implements SyncSlave.
+func (o *SineOsc) Sync(ago float64) (y, jump float64) {
+	w := ago * cmplx.Phase(o.d)
+	before := imag(o.x * cmplx.Exp(complex(0, -w)))
+	o.x = cmplx.Exp(complex(0, w))
+	return imag(o.x), -before
+}
+
 func (o *SineOsc) Sing() float64 {
 	o.x *= o.d
+	o.n++
+	if o.n == renormPeriod {
+		o.n = 0
+		o.x = renormalize(o.x)
+	}
 	return imag(o.x)
 }
 
+// renormPeriod is the number of samples between renormalizations of a rotating phasor, whose magnitude
+// otherwise drifts due to rounding errors by as much as 1e-16 per sample.
+const renormPeriod = 1 << 12
+
+// renormalize restores x to unit magnitude using a single Newton step, which is accurate for x near the unit circle.
+func renormalize(x complex128) complex128 {
+	m := real(x)*real(x) + imag(x)*imag(x)
+	return x * complex((3-m)/2, 0)
+}
+
 type SinePM struct {
 	pidt  float64
 	freq  float64
@@ -65,6 +140,18 @@ func (o *SinePM) Freq(freq float64) *SinePM {
 	return o
 }
 
+// FM modulates the frequency set by Freq by adding hz, which may make the instantaneous frequency negative.
+// The modulation lasts until the next call to FM or Freq.
+func (o *SinePM) FM(hz float64) *SinePM {
+	o.step = 2 * (o.freq + hz) * o.pidt
+	return o
+}
+
+// ExpFM modulates the frequency set by Freq exponentially, by the given number of octaves.
+func (o *SinePM) ExpFM(octaves float64) *SinePM {
+	return o.FM(o.freq * (math.Exp2(octaves) - 1))
+}
+
 func (o *SinePM) PM(pm float64) *SinePM {
 	o.pm = pm * math.Pi
 	return o
@@ -75,10 +162,33 @@ func (o *SinePM) Phase(phase float64) *SinePM {
 	return o
 }
 
+// Reset sets the phase (0..1) of the next sample, for deterministic retriggering.
+func (o *SinePM) Reset(phase float64) *SinePM {
+	o.phase = math.Remainder(2*math.Pi*phase-o.step, 2*math.Pi)
+	return o
+}
+
+// Wrapped implements SyncMaster.  The phase wraps where the (unmodulated) sine crosses zero upwards.
+func (o *SinePM) Wrapped() (float64, bool) {
+	if o.phase < 0 || o.phase-o.step >= 0 {
+		return 0, false
+	}
+	return o.phase / o.step, true
+}
+
+// Sync implements SyncSlave.
+func (o *SinePM) Sync(ago float64) (y, jump float64) {
+	before := math.Sin(o.phase - ago*o.step + o.pm)
+	o.phase = ago * o.step
+	return math.Sin(o.phase + o.pm), math.Sin(o.pm) - before
+}
+
 func (o *SinePM) Sing() float64 {
 	o.phase += o.step
 	if o.phase > math.Pi {
 		o.phase -= 2 * math.Pi
+	} else if o.phase < -math.Pi {
+		o.phase += 2 * math.Pi
 	}
 	return math.Sin(o.phase + o.pm)
 }
@@ -86,8 +196,12 @@ func (o *SinePM) Sing() float64 {
 type SineSelfPM struct {
 	pidt  float64
 	freq  float64
+	fm    float64 // added to freq, until the next call to Freq
 	index float64
+	exact bool
 	x, d  complex128
+	prev  complex128 // x before the last sample, for Wrapped and Sync
+	n     int
 }
 
 func (o *SineSelfPM) InitAudio(p Params) {
@@ -100,6 +214,25 @@ func (o *SineSelfPM) InitAudio(p Params) {
 
 func (o *SineSelfPM) Freq(freq float64) *SineSelfPM {
 	o.freq = freq
+	o.fm = 0
+	return o
+}
+
+// FM modulates the frequency set by Freq by adding hz, which may make the instantaneous frequency negative.
+// The modulation lasts until the next call to FM, ExpFM or Freq.
+func (o *SineSelfPM) FM(hz float64) *SineSelfPM {
+	o.fm = hz
+	return o
+}
+
+// ExpFM modulates the frequency set by Freq exponentially, by the given number of octaves.
+func (o *SineSelfPM) ExpFM(octaves float64) *SineSelfPM {
+	return o.FM(o.freq * (math.Exp2(octaves) - 1))
+}
+
+// Exact sets whether the rotation is computed accurately (see expwdt) rather than approximately.
+func (o *SineSelfPM) Exact(exact bool) *SineSelfPM {
+	o.exact = exact
 	return o
 }
 
@@ -113,27 +246,67 @@ func (o *SineSelfPM) Phase(phase float64) *SineSelfPM {
 	return o
 }
 
+// Reset sets the phase (0..1) of the next sample, for deterministic retriggering.  It is exact for an index
+// of zero and approximate otherwise.
+func (o *SineSelfPM) Reset(phase float64) *SineSelfPM {
+	x := cmplx.Exp(complex(0, 2*math.Pi*phase))
+	o.x = x * cmplx.Conj(o.rotation((o.freq+o.fm)*o.pidt/(1-o.index*real(x))))
+	return o
+}
+
+// Wrapped implements SyncMaster.  The phase wraps where the sine crosses zero upwards.
+func (o *SineSelfPM) Wrapped() (float64, bool) {
+	if imag(o.prev) >= 0 || imag(o.x) < 0 || real(o.x) <= 0 {
+		return 0, false
+	}
+	return cmplx.Phase(o.x) / cmplx.Phase(o.x*cmplx.Conj(o.prev)), true
+}
+
+// Sync implements SyncSlave.  Like Reset, it is exact for an index of zero and approximate otherwise.
+func (o *SineSelfPM) Sync(ago float64) (y, jump float64) {
+	w := cmplx.Phase(o.x * cmplx.Conj(o.prev))
+	before := imag(o.prev * cmplx.Exp(complex(0, (1-ago)*w)))
+	w = cmplx.Phase(o.rotation((o.freq + o.fm) * o.pidt / (1 - o.index))) // the rate at phase zero
+	o.x = cmplx.Exp(complex(0, ago*w))
+	return imag(o.x), -before
+}
+
 func (o *SineSelfPM) Sing() float64 {
 	const (
 		maxStepSize = 0.05
 		maxSteps    = 100
 	)
 
-	wdt_2 := o.freq * o.pidt / (1 - o.index*real(o.x))
-	if wdt_2 < maxStepSize {
-		o.x *= o.expwdt_approx(wdt_2)
+	o.prev = o.x
+	o.n++
+	if o.n == renormPeriod {
+		o.n = 0
+		o.x = renormalize(o.x)
+	}
+
+	freq := o.freq + o.fm
+	wdt_2 := freq * o.pidt / (1 - o.index*real(o.x))
+	if math.Abs(wdt_2) < maxStepSize {
+		o.x *= o.rotation(wdt_2)
 		return imag(o.x)
 	}
 
-	steps := math.Min(maxSteps, math.Ceil(wdt_2/maxStepSize))
+	steps := math.Min(maxSteps, math.Ceil(math.Abs(wdt_2)/maxStepSize))
 	for i := 0.; i < steps; i++ {
-		wdt_2 := o.freq * o.pidt / ((1 - o.index*real(o.x)) * steps)
-		o.x *= o.expwdt_approx(wdt_2)
+		wdt_2 := freq * o.pidt / ((1 - o.index*real(o.x)) * steps)
+		o.x *= o.rotation(wdt_2)
 	}
 
 	return imag(o.x)
 }
 
+func (o *SineSelfPM) rotation(wdt_2 float64) complex128 {
+	if o.exact {
+		return expwdt(wdt_2)
+	}
+	return o.expwdt_approx(wdt_2)
+}
+
 func (o *SineSelfPM) expwdt_approx(wdt_2 float64) complex128 {
 	// an optimization of complex(1, wdt_2) / complex(1, -wdt_2):
 	wdt_22 := wdt_2 * wdt_2
@@ -142,9 +315,10 @@ func (o *SineSelfPM) expwdt_approx(wdt_2 float64) complex128 {
 }
 
 type SawOsc struct {
-	dt   float64
-	freq float64
-	x, d float64
+	dt      float64
+	freq    float64
+	x, d    float64
+	wrapped bool
 }
 
 func (o *SawOsc) InitAudio(p Params) {
@@ -163,10 +337,35 @@ func (o *SawOsc) Phase(x float64) *SawOsc {
 	return o
 }
 
+// Reset sets the phase (0..1) of the next sample, for deterministic retriggering.
+func (o *SawOsc) Reset(phase float64) *SawOsc {
+	o.x = 2*phase - 1 - o.d
+	return o
+}
+
 func (o *SawOsc) Sing() float64 {
 	o.x += o.d
-	if o.x > 1 {
+	o.wrapped = o.x > 1
+	if o.wrapped {
 		o.x -= 2
 	}
 	return o.x
 }
+
+// Wrapped implements SyncMaster.
+func (o *SawOsc) Wrapped() (float64, bool) {
+	if !o.wrapped {
+		return 0, false
+	}
+	return (o.x + 1) / o.d, true
+}
+
+// Sync implements SyncSlave.
+func (o *SawOsc) Sync(ago float64) (y, jump float64) {
+	before := o.x - ago*o.d
+	if before < -1 {
+		before += 2
+	}
+	o.x = -1 + ago*o.d
+	return o.x, -1 - before
+}
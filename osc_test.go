@@ -32,6 +32,139 @@ func TestSineOsc_expwdt_approx(t *testing.T) {
 	}
 }
 
+func TestSineOsc_FM(t *testing.T) {
+	const (
+		sampleRate = 48000
+		carrier    = 440
+		deviation  = 1000.0 // through zero
+		modulator  = 110
+	)
+
+	var osc SineOsc
+	var pm SinePM
+	var selfPM SineSelfPM
+	for _, o := range []struct {
+		name  string
+		init  func()
+		fm    func(hz float64) float64
+		expFM func(octaves float64) float64
+	}{
+		{"SineOsc",
+			func() { Init(osc.Freq(carrier).Phase(0), Params{sampleRate}) },
+			func(hz float64) float64 { return osc.FM(hz).Sing() },
+			func(octaves float64) float64 { return osc.ExpFM(octaves).Sing() }},
+		{"SinePM",
+			func() { Init(pm.Freq(carrier).Phase(0), Params{sampleRate}) },
+			func(hz float64) float64 { return pm.FM(hz).Sing() },
+			func(octaves float64) float64 { return pm.ExpFM(octaves).Sing() }},
+		{"SineSelfPM", // with an index of zero, it is a plain sine
+			func() { Init(selfPM.Freq(carrier).Exact(true).Phase(0), Params{sampleRate}) },
+			func(hz float64) float64 { return selfPM.FM(hz).Sing() },
+			func(octaves float64) float64 { return selfPM.ExpFM(octaves).Sing() }},
+	} {
+		o.init()
+		maxErr := 0.0
+		for i := 1; i <= sampleRate; i++ {
+			// modulate with the instantaneous frequency at the middle of the sample
+			y := o.fm(deviation * math.Sin(2*math.Pi*modulator*(float64(i)-.5)/sampleRate))
+
+			t := float64(i) / sampleRate
+			phase := 2*math.Pi*carrier*t + deviation/modulator*(1-math.Cos(2*math.Pi*modulator*t))
+			maxErr = math.Max(maxErr, math.Abs(y-math.Sin(phase)))
+		}
+		if maxErr > 1e-3 {
+			t.Errorf("%s FM: expected error below 1e-3, got %g", o.name, maxErr)
+		}
+
+		o.init()
+		for i := 1; i <= sampleRate; i++ {
+			y := o.expFM(1)
+			if e := math.Abs(y - math.Sin(2*math.Pi*2*carrier*float64(i)/sampleRate)); e > 1e-6 {
+				t.Errorf("%s ExpFM: sample %d: error %g", o.name, i, e)
+				break
+			}
+		}
+	}
+
+	// Through-zero FM keeps the amplitude constant.
+	Init(osc.Freq(carrier), Params{sampleRate})
+	maxAmpErr := 0.0
+	for i := 1; i <= sampleRate; i++ {
+		osc.FM(deviation * math.Sin(2*math.Pi*modulator*float64(i)/sampleRate)).Sing()
+		maxAmpErr = math.Max(maxAmpErr, math.Abs(cmplx.Abs(osc.x)-1))
+	}
+	if maxAmpErr > 1e-9 {
+		t.Errorf("expected amplitude error below 1e-9, got %g", maxAmpErr)
+	}
+
+	// Freq ends the modulation.
+	Init(selfPM.Freq(carrier).Phase(0), Params{sampleRate})
+	selfPM.FM(deviation).Freq(carrier)
+	for i := 1; i <= 100; i++ {
+		if e := math.Abs(selfPM.Sing() - math.Sin(2*math.Pi*carrier*float64(i)/sampleRate)); e > 1e-6 {
+			t.Fatalf("SineSelfPM after Freq: sample %d: error %g", i, e)
+		}
+	}
+}
+
+func TestSineOsc_longRun(t *testing.T) {
+	const (
+		sampleRate = 48000
+		freq       = 375 // exactly 1/128 cycle per sample
+	)
+	n := int64(1e9)
+	if testing.Short() {
+		n = 1e7
+	}
+
+	var osc SineOsc
+	Init(osc.Freq(freq).Exact(true), Params{sampleRate})
+	maxAmpErr := 0.0
+	for i := int64(1); i <= n; i++ {
+		osc.Sing()
+		if i%(1<<20) == 0 {
+			maxAmpErr = math.Max(maxAmpErr, math.Abs(cmplx.Abs(osc.x)-1))
+		}
+	}
+	if maxAmpErr > 1e-12 {
+		t.Errorf("expected amplitude error below 1e-12, got %g", maxAmpErr)
+	}
+	// After a whole number of cycles, the phase should be back at zero.
+	freqErr := cmplx.Phase(osc.x) / (2 * math.Pi) / (float64(n) / sampleRate)
+	if math.Abs(freqErr) > 1e-9 {
+		t.Errorf("expected frequency error below 1e-9 Hz, got %g Hz", freqErr)
+	}
+}
+
+func TestSineSelfPM_longRun(t *testing.T) {
+	n := int64(1e7)
+	if testing.Short() {
+		n = 1e5
+	}
+
+	var osc SineSelfPM
+	Init(osc.Freq(1234).Index(.5).Exact(true), Params{48000})
+	maxAmpErr := 0.0
+	for i := int64(1); i <= n; i++ {
+		osc.Sing()
+		if i%(1<<16) == 0 {
+			maxAmpErr = math.Max(maxAmpErr, math.Abs(cmplx.Abs(osc.x)-1))
+		}
+	}
+	if maxAmpErr > 1e-12 {
+		t.Errorf("expected amplitude error below 1e-12, got %g", maxAmpErr)
+	}
+}
+
+func BenchmarkSineOsc_FM(b *testing.B) {
+	o := new(SineOsc)
+	Init(o.Freq(1234), Params{96000})
+	for i := 0; i < b.N; i++ {
+		o.FM(100)
+		o.Sing()
+	}
+}
+
 func BenchmarkSineOsc(b *testing.B) {
 	o := new(SineOsc)
 	Init(o, Params{96000})
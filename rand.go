@@ -2,9 +2,42 @@ package audio
 
 import (
 	"math/rand"
+	"sync"
 	"time"
 )
 
+var seeds struct {
+	sync.Mutex
+	rand *rand.Rand
+}
+
+// SetSeed makes random units created afterwards (SlowRand, Reverb, the noise generators, etc.) reproducible
+// by drawing their seeds from a sequence determined by seed.  To render bit-identical output, call SetSeed
+// before constructing and initializing the Voices to be rendered.
+func SetSeed(seed int64) {
+	seeds.Lock()
+	seeds.rand = rand.New(rand.NewSource(seed))
+	seeds.Unlock()
+}
+
+// ClearSeed undoes SetSeed, so that random units are once again seeded from the clock.
+func ClearSeed() {
+	seeds.Lock()
+	seeds.rand = nil
+	seeds.Unlock()
+}
+
+// NewSeed returns a seed for a new random unit.  Random units should call it rather than seeding themselves
+// so that SetSeed can make them reproducible.
+func NewSeed() int64 {
+	seeds.Lock()
+	defer seeds.Unlock()
+	if seeds.rand == nil {
+		return time.Now().UnixNano()
+	}
+	return seeds.rand.Int63()
+}
+
 type SlowRand struct {
 	freq  float64
 	i, n  int
@@ -16,7 +49,7 @@ type SlowRand struct {
 func NewSlowRand(freq float64) *SlowRand {
 	return &SlowRand{
 		freq: freq,
-		rand: rand.New(rand.NewSource(time.Now().UnixNano())),
+		rand: rand.New(rand.NewSource(NewSeed())),
 	}
 }
 
@@ -0,0 +1,26 @@
+package audio
+
+import "testing"
+
+func TestSetSeed(t *testing.T) {
+	defer ClearSeed()
+
+	render := func() []float64 {
+		SetSeed(7)
+		var r Reverb
+		var n WhiteNoise
+		Init(&r, Params{48000})
+		Init(&n, Params{48000})
+		x := make([]float64, 10000)
+		for i := range x {
+			x[i] = r.Filter(n.Sing())
+		}
+		return x
+	}
+	a, b := render(), render()
+	for i := range a {
+		if a[i] != b[i] {
+			t.Fatalf("sample %d: %v != %v", i, a[i], b[i])
+		}
+	}
+}
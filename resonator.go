@@ -0,0 +1,265 @@
+package audio
+
+import (
+	"math"
+	"math/cmplx"
+	"sort"
+)
+
+// A Mode is a damped resonance.  Decay is the time in seconds in which it decays by 60 dB.
+type Mode struct {
+	Freq, Decay, Gain float64
+}
+
+// ResonatorBank is a set of resonant modes, as of a bar, bell or plate, which ring when excited by impulses
+// or filter an arbitrary input.  Each mode is a complex one-pole filter, so modes may be retuned while
+// ringing without clicks.
+type ResonatorBank struct {
+	srate     float64
+	transpose float64
+	modes     []resonatorMode
+}
+
+type resonatorMode struct {
+	Mode
+	r complex128 // pole
+	y complex128
+}
+
+func NewResonatorBank(modes []Mode) *ResonatorBank {
+	b := &ResonatorBank{transpose: 1}
+	b.SetModes(modes)
+	return b
+}
+
+func (b *ResonatorBank) InitAudio(p Params) {
+	b.srate = p.SampleRate
+	for i := range b.modes {
+		b.setPole(i)
+	}
+}
+
+// SetModes replaces the modes, silencing the bank.
+func (b *ResonatorBank) SetModes(modes []Mode) {
+	b.modes = make([]resonatorMode, len(modes))
+	for i, m := range modes {
+		b.modes[i].Mode = m
+		b.setPole(i)
+	}
+}
+
+// Len returns the number of modes.
+func (b *ResonatorBank) Len() int { return len(b.modes) }
+
+// Mode returns mode i.
+func (b *ResonatorBank) Mode(i int) Mode { return b.modes[i].Mode }
+
+// SetMode changes mode i without interrupting its ringing.
+func (b *ResonatorBank) SetMode(i int, m Mode) {
+	b.modes[i].Mode = m
+	b.setPole(i)
+}
+
+// Transpose scales the frequencies of all modes by ratio.
+func (b *ResonatorBank) Transpose(ratio float64) *ResonatorBank {
+	if ratio != b.transpose {
+		b.transpose = ratio
+		for i := range b.modes {
+			b.setPole(i)
+		}
+	}
+	return b
+}
+
+func (b *ResonatorBank) setPole(i int) {
+	if b.srate == 0 {
+		return // not yet initialized
+	}
+	m := &b.modes[i]
+	w := 2 * math.Pi * m.Freq * b.transpose / b.srate
+	if w >= math.Pi {
+		m.r = 0 // above the Nyquist frequency
+		return
+	}
+	m.r = cmplx.Rect(math.Pow(.001, 1/(m.Decay*b.srate)), w)
+}
+
+// Strike excites every mode with an impulse of the given amplitude.
+func (b *ResonatorBank) Strike(amplitude float64) {
+	for i := range b.modes {
+		b.StrikeMode(i, amplitude)
+	}
+}
+
+// StrikeMode excites mode i with an impulse of the given amplitude.
+func (b *ResonatorBank) StrikeMode(i int, amplitude float64) {
+	m := &b.modes[i]
+	if m.r != 0 {
+		m.y += complex(amplitude*m.Gain, 0)
+	}
+}
+
+// Filter passes x through the modes in parallel.
+func (b *ResonatorBank) Filter(x float64) float64 {
+	y := 0.0
+	for i := range b.modes {
+		m := &b.modes[i]
+		m.y = m.r*m.y + complex(m.Gain*x, 0)
+		y += imag(m.y)
+	}
+	return y
+}
+
+func (b *ResonatorBank) Sing() float64 {
+	return b.Filter(0)
+}
+
+// Done reports whether the bank has fallen silent.
+func (b *ResonatorBank) Done() bool {
+	for _, m := range b.modes {
+		if math.Abs(real(m.y))+math.Abs(imag(m.y)) > 1e-5 {
+			return false
+		}
+	}
+	return true
+}
+
+// BarModes returns the first n modes of a uniform bar with free ends (as of a glockenspiel or
+// marimba without tuning), with a fundamental of freq Hz decaying in decay seconds.  Higher modes decay faster.
+func BarModes(freq, decay float64, n int) []Mode {
+	modes := make([]Mode, n)
+	for i := range modes {
+		// roots of cos(x)cosh(x) = 1
+		x := (float64(i) + 1.5) * math.Pi
+		if i == 0 {
+			x = 4.7300
+		}
+		ratio := x * x / (4.7300 * 4.7300)
+		modes[i] = Mode{freq * ratio, decay / math.Sqrt(ratio), 1 / float64(i+1)}
+	}
+	return modes
+}
+
+// BellModes returns the modes of a church bell with a strike tone of freq Hz, decaying in decay seconds.
+// The hum tone, an octave below, rings longest.
+func BellModes(freq, decay float64) []Mode {
+	partials := []struct{ ratio, decay, gain float64 }{
+		{.5, 1, .6},
+		{1, .7, .8},
+		{1.183, .6, .5}, // minor third
+		{1.506, .45, .4},
+		{2, .4, 1}, // nominal
+		{2.514, .3, .4},
+		{2.662, .3, .4},
+		{3.011, .25, .3},
+		{4.166, .2, .25},
+		{5.433, .15, .2},
+		{6.796, .12, .15},
+		{8.215, .1, .1},
+	}
+	modes := make([]Mode, len(partials))
+	for i, p := range partials {
+		modes[i] = Mode{freq * p.ratio, decay * p.decay, p.gain}
+	}
+	return modes
+}
+
+// PlateModes returns the n lowest modes of a thin rectangular plate with simply supported edges and the
+// given aspect ratio (width/height), with a fundamental of freq Hz decaying in decay seconds.
+func PlateModes(freq, decay, aspect float64, n int) []Mode {
+	var modes []Mode
+	f := func(i, j int) float64 {
+		x, y := float64(i)/aspect, float64(j)
+		return x*x + y*y
+	}
+	f11 := f(1, 1)
+	for i := 1; i <= n; i++ {
+		for j := 1; j <= n; j++ {
+			ratio := f(i, j) / f11
+			modes = append(modes, Mode{freq * ratio, decay / math.Sqrt(ratio), 1 / math.Sqrt(ratio)})
+		}
+	}
+	sort.Slice(modes, func(i, j int) bool { return modes[i].Freq < modes[j].Freq })
+	return modes[:n]
+}
+
+// ModalSynth is an Instrument that plays notes on a ResonatorBank per note, such as a set of chimes.
+// The frequencies of Modes are relative to the note's frequency; see BarModes, BellModes and PlateModes
+// (with a freq of 1).
+type ModalSynth struct {
+	Params Params
+	Modes  []Mode
+	voices MultiVoice
+}
+
+// StrikeNote is the note type of ModalSynth.  Pitch is log2 of the frequency (see MIDINotePitch) and may
+// change while the note rings.  Position (0..1, from one end of a bar to the other) and Force (0..1) are the
+// place and strength of the strike, taken at the start of the note.  A harder strike is louder and brighter.
+// A note lasts until it has rung out.
+type StrikeNote struct {
+	Pitch, Position, Force []*ControlPoint
+}
+
+func (s *ModalSynth) InitAudio(p Params) {
+	s.Params = p
+	s.voices.Params = p
+	Init(s.voices.Voices, p)
+}
+
+func (s *ModalSynth) Play(n StrikeNote) {
+	v := &modalVoice{bank: NewResonatorBank(s.Modes)}
+	v.pitch.points = n.Pitch
+	v.pos.points = n.Position
+	v.force.points = n.Force
+	s.voices.Add(v)
+}
+
+func (s *ModalSynth) Sing() float64 {
+	return s.voices.Sing()
+}
+
+func (s *ModalSynth) Done() bool {
+	return s.voices.Done()
+}
+
+func (s *ModalSynth) Stop() {
+	s.voices.Stop()
+}
+
+type modalVoice struct {
+	bank              *ResonatorBank
+	pitch, pos, force Control
+	struck            bool
+}
+
+func (v *modalVoice) InitAudio(p Params) {
+	v.bank.InitAudio(p)
+	v.pitch.InitAudio(p)
+	v.pos.InitAudio(p)
+	v.force.InitAudio(p)
+}
+
+func (v *modalVoice) Sing() float64 {
+	v.bank.Transpose(math.Exp2(v.pitch.Sing()))
+	pos, force := v.pos.Sing(), v.force.Sing()
+	if !v.struck {
+		v.struck = true
+		v.strike(pos, force)
+	}
+	return v.bank.Sing()
+}
+
+// strike excites each mode in proportion to its shape at the strike position, approximating the k'th
+// mode of a free bar, with k+2 nodes, as a cosine.  A harder strike is shorter and so excites more high modes.
+func (v *modalVoice) strike(pos, force float64) {
+	cutoff := 2 + 30*force*force
+	for k := 0; k < v.bank.Len(); k++ {
+		ratio := v.bank.Mode(k).Freq / v.bank.Mode(0).Freq
+		shape := math.Cos(math.Pi * float64(k+2) * pos)
+		v.bank.StrikeMode(k, force*shape/(1+(ratio/cutoff)*(ratio/cutoff)))
+	}
+}
+
+func (v *modalVoice) Done() bool {
+	return v.struck && v.pitch.Done() && v.bank.Done()
+}
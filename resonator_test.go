@@ -0,0 +1,74 @@
+package audio
+
+import (
+	"math"
+	"testing"
+)
+
+func TestResonatorBank(t *testing.T) {
+	const sampleRate = 48000
+	b := NewResonatorBank([]Mode{{Freq: 1000, Decay: .5, Gain: 1}})
+	Init(b, Params{sampleRate})
+	b.Strike(1)
+	x := make([]float64, sampleRate/2)
+	for i := range x {
+		x[i] = b.Sing()
+	}
+	if f := estimateFreq(x, sampleRate, 1000); math.Abs(f-1000) > .1 {
+		t.Errorf("expected frequency 1000, got %f", f)
+	}
+	if db := 20 * math.Log10(rms(x[len(x)-4800:])/rms(x[:4800])); math.Abs(db+48) > 1 {
+		t.Errorf("expected a decay of 48 dB over .4 seconds, got %f", db)
+	}
+}
+
+func TestPlateModes(t *testing.T) {
+	// The modes of a simply supported plate are proportional to (i/aspect)² + j².
+	for _, c := range []struct {
+		aspect float64
+		ratios []float64
+	}{
+		{1, []float64{1, 2.5, 2.5, 4}},
+		{2, []float64{1, 1.6, 2.6, 3.4}},
+	} {
+		modes := PlateModes(100, 1, c.aspect, len(c.ratios))
+		for i, r := range c.ratios {
+			if f := modes[i].Freq; math.Abs(f-100*r) > 1e-9 {
+				t.Errorf("aspect %v: expected mode %d at %v Hz, got %v", c.aspect, i, 100*r, f)
+			}
+		}
+	}
+}
+
+func TestModalSynth(t *testing.T) {
+	const sampleRate = 48000
+	amplitude := func(pos float64) float64 {
+		s := &ModalSynth{Modes: BarModes(1, 1, 5)}
+		Init(s, Params{sampleRate})
+		s.Play(StrikeNote{
+			Pitch:    []*ControlPoint{{Time: 0, Value: math.Log2(500)}},
+			Position: []*ControlPoint{{Time: 0, Value: pos}},
+			Force:    []*ControlPoint{{Time: 0, Value: 1}},
+		})
+		x := make([]float64, sampleRate/10)
+		for i := range x {
+			x[i] = s.Sing()
+		}
+		// Measure the fundamental alone by correlating with a 500 Hz sinusoid.
+		var c complex128
+		for i, x := range x {
+			c += complex(x, 0) * complex(math.Cos(2*math.Pi*500*float64(i)/sampleRate), math.Sin(2*math.Pi*500*float64(i)/sampleRate))
+		}
+		n := 0
+		for ; !s.Done() && n < 10*sampleRate; n++ {
+			s.Sing()
+		}
+		if n == 10*sampleRate {
+			t.Errorf("expected the note to ring out")
+		}
+		return real(c)*real(c) + imag(c)*imag(c)
+	}
+	if end, node := amplitude(0), amplitude(.25); node > end/1000 {
+		t.Errorf("expected striking at a node to suppress the fundamental: %g vs %g", node, end)
+	}
+}
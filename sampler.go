@@ -0,0 +1,409 @@
+package audio
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Sampler is an Instrument that plays recorded samples, chosen for each note by key and velocity zones and
+// repitched with band-limited (windowed sinc) interpolation.
+type Sampler struct {
+	Params Params
+	Zones  []*SampleZone
+	// Attack and Release are the times in seconds of the amplitude envelope of each note.
+	Attack, Release float64
+	voices          MultiVoice
+}
+
+// A SampleZone maps a range of keys and velocities to a sample.  All zones matching a note are played.
+type SampleZone struct {
+	Sample     []float64
+	SampleRate float64
+	// Root is the MIDI key at which the sample plays at its recorded pitch.  Tune is added, in cents.
+	Root, Tune float64
+	// LoKey..HiKey and LoVel..HiVel (0..1) are the inclusive ranges of notes that play the zone.
+	LoKey, HiKey int
+	LoVel, HiVel float64
+	// Attenuation is in dB.
+	Attenuation float64
+	// Loop, if not NoLoop, repeats the samples from LoopStart up to (not including) LoopEnd.  The last
+	// Crossfade samples of the loop are crossfaded with those before LoopStart, smoothing the join.
+	Loop                          LoopMode
+	LoopStart, LoopEnd, Crossfade int
+	// Envelope, if not nil, replaces the Sampler's Attack and Release for this zone.
+	Envelope *ZoneEnvelope
+	// Cutoff, if nonzero, is the frequency in Hz of a two-pole lowpass filter with a peak of Resonance dB.
+	Cutoff, Resonance float64
+}
+
+// ZoneEnvelope is an amplitude envelope with times in seconds.  Sustain is a level (0..1).
+type ZoneEnvelope struct {
+	Delay, Attack, Hold, Decay, Sustain, Release float64
+}
+
+type LoopMode int
+
+const (
+	NoLoop         LoopMode = iota
+	LoopContinuous          // loop until the end of the note's release
+	LoopSustain             // loop until the note is released, then play on to the end of the sample
+)
+
+// SamplerNote is the note type of Sampler.  Pitch is log2 of the frequency (see MIDINotePitch) and may change
+// during the note.  Velocity (0..1) and Duration (in seconds, after which the note is released) are taken
+// at the start of the note.  Without a Duration, the note is released at the end of its Pitch and Velocity
+// controls, like the notes of other instruments.
+type SamplerNote struct {
+	Pitch, Velocity, Duration []*ControlPoint
+}
+
+func NewSampler(zones ...*SampleZone) *Sampler {
+	return &Sampler{Zones: zones, Attack: .002, Release: .1}
+}
+
+func (s *Sampler) InitAudio(p Params) {
+	s.Params = p
+	s.voices.Params = p
+	Init(s.voices.Voices, p)
+}
+
+func (s *Sampler) Play(n SamplerNote) {
+	if len(n.Pitch) == 0 || len(n.Velocity) == 0 {
+		return
+	}
+	key := 69 + 12*(n.Pitch[0].Value-math.Log2(440))
+	vel := n.Velocity[0].Value
+	for _, z := range s.Zones {
+		if z.matches(key, vel) {
+			v := &samplerVoice{sampler: s, zone: z}
+			v.pitch.points = n.Pitch
+			v.vel.points = n.Velocity
+			v.dur.points = n.Duration
+			s.voices.Add(v)
+		}
+	}
+}
+
+func (z *SampleZone) matches(key, vel float64) bool {
+	k := int(math.Floor(key + .5))
+	return k >= z.LoKey && k <= z.HiKey && vel >= z.LoVel && vel <= z.HiVel
+}
+
+func (s *Sampler) Sing() float64 {
+	return s.voices.Sing()
+}
+
+func (s *Sampler) Done() bool {
+	return s.voices.Done()
+}
+
+func (s *Sampler) Stop() {
+	s.voices.Stop()
+}
+
+type samplerVoice struct {
+	sampler         *Sampler
+	zone            *SampleZone
+	pitch, vel, dur Control
+	dt              float64
+	env             ExpEnv
+	filter          lowPass2
+	pos             float64
+	gain            float64
+	t, duration     float64
+	started         bool
+	released, ended bool
+}
+
+func (v *samplerVoice) InitAudio(p Params) {
+	v.dt = 1 / p.SampleRate
+	v.pitch.InitAudio(p)
+	v.vel.InitAudio(p)
+	v.dur.InitAudio(p)
+	v.env.InitAudio(p)
+	if z := v.zone; z.Cutoff > 0 {
+		v.filter.set(z.Cutoff*v.dt, math.Max(math.Sqrt(.5), math.Pow(10, z.Resonance/20)))
+	}
+}
+
+func (v *samplerVoice) Sing() float64 {
+	z := v.zone
+	pitch := v.pitch.Sing()
+	vel := v.vel.Sing()
+	dur := v.dur.Sing()
+	if !v.started {
+		v.started = true
+		v.gain = vel * math.Pow(10, -z.Attenuation/20)
+		v.duration = dur
+		if len(v.dur.points) == 0 {
+			v.duration = math.Max(v.pitch.Duration(), v.vel.Duration())
+		}
+		if e := z.Envelope; e != nil {
+			v.env.Go(0, e.Delay).Go(1, e.Attack).Go(1, e.Hold).Go(e.Sustain, e.Decay)
+		} else {
+			v.env.Go(1, v.sampler.Attack)
+		}
+	}
+	if !v.released && v.t >= v.duration {
+		v.released = true
+		release := v.sampler.Release
+		if z.Envelope != nil {
+			release = z.Envelope.Release
+		}
+		v.env.ReleaseNow(release)
+	}
+	v.t += v.dt
+
+	if v.ended {
+		return 0
+	}
+	root := 440 * math.Exp2((z.Root+z.Tune/100-69)/12)
+	rate := math.Exp2(pitch) / root * z.SampleRate * v.dt
+	y := v.read(v.pos, rate)
+	v.pos += rate
+	if v.looping() {
+		for v.pos >= float64(z.LoopEnd) {
+			v.pos -= float64(z.LoopEnd - z.LoopStart)
+		}
+	} else if v.pos >= float64(len(z.Sample)) {
+		v.ended = true
+	}
+	if z.Cutoff > 0 {
+		y = v.filter.filter(y)
+	}
+	return v.gain * v.env.Sing() * y
+}
+
+func (v *samplerVoice) looping() bool {
+	z := v.zone
+	if z.LoopEnd <= z.LoopStart || z.LoopStart < 0 || z.LoopEnd > len(z.Sample) {
+		return false
+	}
+	return z.Loop == LoopContinuous || z.Loop == LoopSustain && !v.released
+}
+
+// at returns sample i, following the loop if looping.
+func (v *samplerVoice) at(i int) float64 {
+	z := v.zone
+	looping := v.looping()
+	if looping && i >= z.LoopEnd {
+		i = z.LoopStart + (i-z.LoopStart)%(z.LoopEnd-z.LoopStart)
+	}
+	if i < 0 || i >= len(z.Sample) {
+		return 0
+	}
+	x := z.Sample[i]
+	if looping && i >= z.LoopEnd-z.Crossfade && i < z.LoopEnd {
+		if j := i - (z.LoopEnd - z.LoopStart); j >= 0 {
+			x = Crossfade(x, float64(i-(z.LoopEnd-z.Crossfade)+1)/float64(z.Crossfade+1), z.Sample[j])
+		}
+	}
+	return x
+}
+
+// read interpolates the sample at pos with a windowed sinc, whose cutoff is lowered when the sample is
+// played faster than its sample rate so that transposing up does not alias.
+func (v *samplerVoice) read(pos, rate float64) float64 {
+	c := math.Min(1, 1/rate)
+	w := int(math.Ceil(sincZeros / c))
+	i0 := int(math.Floor(pos))
+	y := 0.0
+	for i := i0 - w + 1; i <= i0+w; i++ {
+		y += v.at(i) * sinc((pos-float64(i))*c)
+	}
+	return c * y
+}
+
+func (v *samplerVoice) Done() bool {
+	return v.ended || v.released && v.env.Done()
+}
+
+// lowPass2 is a resonant two-pole lowpass filter (from Robert Bristow-Johnson's cookbook).
+type lowPass2 struct {
+	b0, b1, b2, a1, a2 float64
+	x1, x2, y1, y2     float64
+}
+
+// set sets the cutoff, as a fraction of the sample rate, and the Q.
+func (f *lowPass2) set(cutoff, q float64) {
+	w := 2 * math.Pi * math.Min(cutoff, .49)
+	alpha := math.Sin(w) / (2 * q)
+	a0 := 1 + alpha
+	f.b1 = (1 - math.Cos(w)) / a0
+	f.b0 = f.b1 / 2
+	f.b2 = f.b0
+	f.a1 = -2 * math.Cos(w) / a0
+	f.a2 = (1 - alpha) / a0
+}
+
+func (f *lowPass2) filter(x float64) float64 {
+	y := f.b0*x + f.b1*f.x1 + f.b2*f.x2 - f.a1*f.y1 - f.a2*f.y2
+	f.x2, f.x1 = f.x1, x
+	f.y2, f.y1 = f.y1, y
+	return y
+}
+
+// sincZeros is the number of zero crossings on each side of the interpolation kernel.
+const sincZeros = 8
+
+const sincResolution = 256
+
+// sincTable holds a Blackman-windowed sinc, sampled at sincResolution points per zero crossing.
+var sincTable = func() []float64 {
+	t := make([]float64, sincZeros*sincResolution+2)
+	t[0] = 1
+	for i := 1; i < len(t); i++ {
+		x := float64(i) / sincResolution
+		if x >= sincZeros {
+			break
+		}
+		w := .42 + .5*math.Cos(math.Pi*x/sincZeros) + .08*math.Cos(2*math.Pi*x/sincZeros)
+		t[i] = w * math.Sin(math.Pi*x) / (math.Pi * x)
+	}
+	return t
+}()
+
+func sinc(x float64) float64 {
+	j, f := math.Modf(math.Abs(x) * sincResolution)
+	i := int(j)
+	if i >= len(sincTable)-1 {
+		return 0
+	}
+	return Crossfade(sincTable[i], f, sincTable[i+1])
+}
+
+// LoadSampleZones reads a zone map description file (see ReadSampleZones), opening the WAV files named in it
+// relative to its directory.
+func LoadSampleZones(path string) ([]*SampleZone, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	dir := filepath.Dir(path)
+	return ReadSampleZones(f, func(name string) (io.ReadCloser, error) {
+		if !filepath.IsAbs(name) {
+			name = filepath.Join(dir, name)
+		}
+		return os.Open(name)
+	})
+}
+
+// ReadSampleZones reads a zone map description, opening the WAV files named in it with open.  Each line
+// describes a zone as space-separated key=value pairs; blank lines and lines starting with # are ignored.
+// For example:
+//	sample=piano-c4.wav root=60 keys=48-66 velocity=0-.5 loop=sustain:1200-48000 crossfade=512
+// The keys are:
+//	sample       the WAV file (required); only its first channel is used
+//	root         the MIDI key of the recorded pitch (default 60)
+//	tune         cents (default 0)
+//	keys         the range of MIDI keys (default 0-127)
+//	velocity     the range of velocities (default 0-1)
+//	attenuation  dB (default 0)
+//	loop         [continuous|sustain:]start-end, in samples (default no loop; default mode sustain)
+//	crossfade    the length of the loop crossfade in samples (default 0)
+func ReadSampleZones(r io.Reader, open func(name string) (io.ReadCloser, error)) ([]*SampleZone, error) {
+	var zones []*SampleZone
+	samples := map[string]*SampleZone{} // already-read files, by name
+	s := bufio.NewScanner(r)
+	for line := 1; s.Scan(); line++ {
+		text := strings.TrimSpace(s.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		z, err := parseSampleZone(text, samples, open)
+		if err != nil {
+			return nil, fmt.Errorf("sample zones: line %d: %s", line, err)
+		}
+		zones = append(zones, z)
+	}
+	return zones, s.Err()
+}
+
+func parseSampleZone(text string, samples map[string]*SampleZone, open func(string) (io.ReadCloser, error)) (*SampleZone, error) {
+	z := &SampleZone{Root: 60, HiKey: 127, HiVel: 1}
+	name := ""
+	for _, field := range strings.Fields(text) {
+		kv := strings.SplitN(field, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("expected key=value, got %q", field)
+		}
+		key, val := kv[0], kv[1]
+		var err error
+		switch key {
+		case "sample":
+			name = val
+		case "root":
+			z.Root, err = strconv.ParseFloat(val, 64)
+		case "tune":
+			z.Tune, err = strconv.ParseFloat(val, 64)
+		case "attenuation":
+			z.Attenuation, err = strconv.ParseFloat(val, 64)
+		case "keys":
+			var lo, hi float64
+			lo, hi, err = parseRange(val)
+			z.LoKey, z.HiKey = int(lo), int(hi)
+		case "velocity":
+			z.LoVel, z.HiVel, err = parseRange(val)
+		case "loop":
+			z.Loop = LoopSustain
+			if i := strings.Index(val, ":"); i >= 0 {
+				switch val[:i] {
+				case "continuous":
+					z.Loop = LoopContinuous
+				case "sustain":
+				default:
+					return nil, fmt.Errorf("unknown loop mode %q", val[:i])
+				}
+				val = val[i+1:]
+			}
+			var start, end float64
+			start, end, err = parseRange(val)
+			z.LoopStart, z.LoopEnd = int(start), int(end)
+		case "crossfade":
+			z.Crossfade, err = strconv.Atoi(val)
+		default:
+			return nil, fmt.Errorf("unknown key %q", key)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", key, err)
+		}
+	}
+	if name == "" {
+		return nil, fmt.Errorf("missing sample")
+	}
+	if s, ok := samples[name]; ok {
+		z.Sample, z.SampleRate = s.Sample, s.SampleRate
+		return z, nil
+	}
+	f, err := open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	channels, sampleRate, err := ReadWAV(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", name, err)
+	}
+	z.Sample, z.SampleRate = channels[0], sampleRate
+	samples[name] = z
+	return z, nil
+}
+
+func parseRange(s string) (lo, hi float64, err error) {
+	i := strings.Index(s, "-")
+	if i < 0 {
+		return 0, 0, fmt.Errorf("expected a range lo-hi, got %q", s)
+	}
+	if lo, err = strconv.ParseFloat(s[:i], 64); err != nil {
+		return
+	}
+	hi, err = strconv.ParseFloat(s[i+1:], 64)
+	return
+}
@@ -0,0 +1,128 @@
+package audio
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestSampler(t *testing.T) {
+	const sampleRate = 48000
+	sine := func(freq float64) []float64 {
+		x := make([]float64, sampleRate/2)
+		for i := range x {
+			x[i] = math.Sin(2 * math.Pi * freq * float64(i) / sampleRate)
+		}
+		return x
+	}
+	low := &SampleZone{Sample: sine(440), SampleRate: sampleRate, Root: 69, LoKey: 0, HiKey: 71, HiVel: 1,
+		Loop: LoopSustain, LoopStart: 1000, LoopEnd: 20000, Crossfade: 1000}
+	high := &SampleZone{Sample: sine(1000), SampleRate: sampleRate, Root: 72, LoKey: 72, HiKey: 127, LoVel: .5, HiVel: 1}
+
+	for _, c := range []struct {
+		key      uint8
+		velocity float64
+		freq     float64
+	}{
+		{69, 1, 440},
+		{64, .3, 440 * math.Exp2(-5./12)},
+		{76, .7, 1000 * math.Exp2(4./12)},
+		{76, .3, 0}, // no zone
+	} {
+		s := NewSampler(low, high)
+		Init(s, Params{sampleRate})
+		s.Play(SamplerNote{
+			Pitch:    []*ControlPoint{{Time: 0, Value: MIDINotePitch(c.key)}},
+			Velocity: []*ControlPoint{{Time: 0, Value: c.velocity}},
+			Duration: []*ControlPoint{{Time: 0, Value: 1}},
+		})
+		var x []float64
+		for !s.Done() && len(x) < 2*sampleRate {
+			x = append(x, s.Sing())
+		}
+		if c.freq == 0 {
+			if len(x) > 1 {
+				t.Errorf("key %d, velocity %v: expected silence", c.key, c.velocity)
+			}
+			continue
+		}
+		if f := estimateFreq(x[sampleRate/10:sampleRate/4], sampleRate, c.freq); math.Abs(f/c.freq-1) > .001 {
+			t.Errorf("key %d: expected frequency %f, got %f", c.key, c.freq, f)
+		}
+		if a := rms(x[sampleRate/10:sampleRate/4]) * math.Sqrt2; math.Abs(a-c.velocity) > .01 {
+			t.Errorf("key %d: expected amplitude %f, got %f", c.key, c.velocity, a)
+		}
+	}
+
+	// The looped sample sustains for the note's duration; the other ends with its sample.
+	for _, c := range []struct {
+		key uint8
+		dur float64
+	}{{69, 1.2}, {72, .5}} {
+		s := NewSampler(low, high)
+		Init(s, Params{sampleRate})
+		s.Play(SamplerNote{
+			Pitch:    []*ControlPoint{{Time: 0, Value: MIDINotePitch(c.key)}},
+			Velocity: []*ControlPoint{{Time: 0, Value: 1}},
+			Duration: []*ControlPoint{{Time: 0, Value: 1}},
+		})
+		n := 0
+		for ; !s.Done() && n < 2*sampleRate; n++ {
+			s.Sing()
+		}
+		if d := float64(n) / sampleRate; math.Abs(d-c.dur) > .05 {
+			t.Errorf("key %d: expected duration %f, got %f", c.key, c.dur, d)
+		}
+	}
+
+	// Without a Duration, the note is released at the end of its controls.
+	s := NewSampler(low, high)
+	Init(s, Params{sampleRate})
+	s.Play(SamplerNote{
+		Pitch:    []*ControlPoint{{Time: 0, Value: MIDINotePitch(69)}, {Time: .6, Value: MIDINotePitch(69)}},
+		Velocity: []*ControlPoint{{Time: 0, Value: 1}},
+	})
+	n, peak := 0, 0.0
+	for ; !s.Done() && n < 2*sampleRate; n++ {
+		peak = math.Max(peak, s.Sing())
+	}
+	if d := float64(n) / sampleRate; math.Abs(d-.8) > .05 || peak < .9 {
+		t.Errorf("without Duration: expected duration .8 and peak 1, got %f and %f", d, peak)
+	}
+}
+
+func TestReadSampleZones(t *testing.T) {
+	const desc = `
+# a test
+sample=a.wav root=57 keys=0-59 loop=continuous:10-90 crossfade=5
+sample=a.wav root=69 keys=60-127 velocity=.5-1 attenuation=6
+`
+	open := func(name string) (io.ReadCloser, error) {
+		if name != "a.wav" {
+			t.Fatalf("unexpected file %s", name)
+		}
+		return ioutil.NopCloser(bytes.NewReader(encodeWAV([][]float64{make([]float64, 100)}, 44100, 1, 16, false))), nil
+	}
+	zones, err := ReadSampleZones(strings.NewReader(desc), open)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(zones) != 2 {
+		t.Fatalf("expected 2 zones, got %d", len(zones))
+	}
+	z := zones[0]
+	if z.Root != 57 || z.HiKey != 59 || z.Loop != LoopContinuous || z.LoopStart != 10 || z.LoopEnd != 90 || z.Crossfade != 5 || len(z.Sample) != 100 || z.SampleRate != 44100 {
+		t.Errorf("wrong zone %+v", *z)
+	}
+	z = zones[1]
+	if z.LoKey != 60 || z.LoVel != .5 || z.HiVel != 1 || z.Attenuation != 6 || z.Loop != NoLoop {
+		t.Errorf("wrong zone %+v", *z)
+	}
+
+	if _, err := ReadSampleZones(strings.NewReader("sample=a.wav keys=x"), open); err == nil {
+		t.Error("expected an error")
+	}
+}
@@ -0,0 +1,325 @@
+package audio
+
+import (
+	"encoding/json"
+	"io"
+	"math"
+	"math/cmplx"
+	"sort"
+
+	"github.com/ktye/fft"
+)
+
+// SinusoidalAnalysis is a sound represented as a set of time-varying sinusoidal partials (tracks), as found by
+// McAulay-Quatieri style analysis.  Tracks may be edited freely before resynthesis.
+type SinusoidalAnalysis struct {
+	SampleRate float64
+	Tracks     []*Track
+}
+
+// A Track is a partial whose frequency and amplitude vary over time.  Its points are in order of time.
+type Track struct {
+	Points []TrackPoint
+}
+
+type TrackPoint struct {
+	Time      float64 // seconds
+	Freq      float64 // Hz
+	Amplitude float64
+	Phase     float64 // radians
+}
+
+// AnalysisOptions configures AnalyzeSinusoids.  Zero fields take default values.
+type AnalysisOptions struct {
+	WindowSize int     // analysis window in samples, a power of 2 (default 2048)
+	Hop        int     // samples between frames (default WindowSize/4)
+	MaxPeaks   int     // maximum number of peaks per frame (default 100)
+	Threshold  float64 // peaks more than this many dB below the loudest in the frame are ignored (default 60)
+	Floor      float64 // peaks below this level in dBFS are ignored (default -90)
+	MaxJump    float64 // maximum relative frequency change between frames for a track to continue (default .03)
+	MinLength  int     // tracks with fewer points are discarded (default 3)
+}
+
+func (o *AnalysisOptions) setDefaults() {
+	if o.WindowSize == 0 {
+		o.WindowSize = 2048
+	}
+	if o.Hop == 0 {
+		o.Hop = o.WindowSize / 4
+	}
+	if o.MaxPeaks == 0 {
+		o.MaxPeaks = 100
+	}
+	if o.Threshold == 0 {
+		o.Threshold = 60
+	}
+	if o.Floor == 0 {
+		o.Floor = -90
+	}
+	if o.MaxJump == 0 {
+		o.MaxJump = .03
+	}
+	if o.MinLength == 0 {
+		o.MinLength = 3
+	}
+}
+
+// AnalyzeSinusoids analyzes x into partial tracks by picking spectral peaks in overlapping frames and
+// connecting peaks of similar frequency in successive frames.
+func AnalyzeSinusoids(x []float64, sampleRate float64, o AnalysisOptions) *SinusoidalAnalysis {
+	o.setDefaults()
+	n := o.WindowSize
+	f, err := fft.New(n)
+	if err != nil {
+		panic(err)
+	}
+	if f.N != n {
+		panic("AnalyzeSinusoids: WindowSize must be a power of 2")
+	}
+	window := make([]float64, n)
+	for i := range window {
+		window[i] = (1 - math.Cos(2*math.Pi*float64(i)/float64(n))) / 2
+	}
+
+	a := &SinusoidalAnalysis{SampleRate: sampleRate}
+	var active []*Track
+	buf := make([]complex128, n)
+	for start := -n / 2; start < len(x); start += o.Hop {
+		// Rotate the frame by half its length so that phases are relative to its center.
+		for i := range buf {
+			j := start + i
+			v := 0.0
+			if j >= 0 && j < len(x) {
+				v = x[j] * window[i]
+			}
+			buf[(i+n/2)%n] = complex(v, 0)
+		}
+		peaks := findPeaks(f.Transform(buf), sampleRate, float64(start+n/2)/sampleRate, o)
+		active = continueTracks(active, peaks, o.MaxJump, func(t *Track) {
+			if len(t.Points) >= o.MinLength {
+				a.Tracks = append(a.Tracks, t)
+			}
+		})
+	}
+	for _, t := range active {
+		if len(t.Points) >= o.MinLength {
+			a.Tracks = append(a.Tracks, t)
+		}
+	}
+
+	// Fade tracks in and out over a hop.
+	hop := float64(o.Hop) / sampleRate
+	for _, t := range a.Tracks {
+		first, last := t.Points[0], t.Points[len(t.Points)-1]
+		first.Time -= hop
+		first.Amplitude = 0
+		first.Phase -= 2 * math.Pi * first.Freq * hop
+		last.Time += hop
+		last.Amplitude = 0
+		last.Phase += 2 * math.Pi * last.Freq * hop
+		t.Points = append(append([]TrackPoint{first}, t.Points...), last)
+	}
+	sort.Slice(a.Tracks, func(i, j int) bool { return a.Tracks[i].Points[0].Time < a.Tracks[j].Points[0].Time })
+	return a
+}
+
+func findPeaks(spectrum []complex128, sampleRate, time float64, o AnalysisOptions) []TrackPoint {
+	n := len(spectrum)
+	db := make([]float64, n/2)
+	max := math.Inf(-1)
+	for i := range db {
+		db[i] = 20 * math.Log10(cmplx.Abs(spectrum[i])*4/float64(n)+1e-12)
+		max = math.Max(max, db[i])
+	}
+	min := math.Max(max-o.Threshold, o.Floor)
+
+	var peaks []TrackPoint
+	for i := 1; i < len(db)-1; i++ {
+		if db[i] < min || db[i] <= db[i-1] || db[i] < db[i+1] {
+			continue
+		}
+		// parabolic interpolation of the log magnitude
+		l, c, r := db[i-1], db[i], db[i+1]
+		d := (l - r) / (2 * (l - 2*c + r))
+		if math.IsNaN(d) {
+			d = 0
+		}
+		peaks = append(peaks, TrackPoint{
+			Time:      time,
+			Freq:      (float64(i) + d) * sampleRate / float64(n),
+			Amplitude: math.Pow(10, (c-(l-r)*d/4)/20),
+			Phase:     cmplx.Phase(spectrum[i]),
+		})
+	}
+	sort.Slice(peaks, func(i, j int) bool { return peaks[i].Amplitude > peaks[j].Amplitude })
+	if len(peaks) > o.MaxPeaks {
+		peaks = peaks[:o.MaxPeaks]
+	}
+	return peaks
+}
+
+// continueTracks extends the active tracks with the nearest unclaimed peaks, strongest tracks first.
+// Tracks that find no peak end (and are passed to done); peaks that continue no track start new ones.
+func continueTracks(active []*Track, peaks []TrackPoint, maxJump float64, done func(*Track)) []*Track {
+	sort.Slice(active, func(i, j int) bool {
+		return active[i].Points[len(active[i].Points)-1].Amplitude > active[j].Points[len(active[j].Points)-1].Amplitude
+	})
+	claimed := make([]bool, len(peaks))
+	var next []*Track
+	for _, t := range active {
+		f := t.Points[len(t.Points)-1].Freq
+		best := -1
+		for i, p := range peaks {
+			if !claimed[i] && math.Abs(p.Freq-f) <= maxJump*f && (best < 0 || math.Abs(p.Freq-f) < math.Abs(peaks[best].Freq-f)) {
+				best = i
+			}
+		}
+		if best < 0 {
+			done(t)
+			continue
+		}
+		claimed[best] = true
+		t.Points = append(t.Points, peaks[best])
+		next = append(next, t)
+	}
+	for i, p := range peaks {
+		if !claimed[i] {
+			next = append(next, &Track{Points: []TrackPoint{p}})
+		}
+	}
+	return next
+}
+
+// Duration returns the time of the end of the last track.
+func (a *SinusoidalAnalysis) Duration() float64 {
+	d := 0.0
+	for _, t := range a.Tracks {
+		d = math.Max(d, t.Points[len(t.Points)-1].Time)
+	}
+	return d
+}
+
+// Save writes the analysis as JSON.
+func (a *SinusoidalAnalysis) Save(w io.Writer) error {
+	return json.NewEncoder(w).Encode(a)
+}
+
+// LoadSinusoidalAnalysis reads an analysis written by Save.
+func LoadSinusoidalAnalysis(r io.Reader) (*SinusoidalAnalysis, error) {
+	a := &SinusoidalAnalysis{}
+	if err := json.NewDecoder(r).Decode(a); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// SinusoidalSynth resynthesizes a SinusoidalAnalysis with a SineOsc per track.  Time stretching and pitch
+// shifting are independent and may be changed while playing.
+type SinusoidalSynth struct {
+	analysis  *SinusoidalAnalysis
+	dt        float64
+	t         float64 // analysis time
+	stretch   float64
+	transpose float64
+	next      int // index of the next track to start
+	active    []*resynthTrack
+	params    Params
+}
+
+type resynthTrack struct {
+	track *Track
+	i     int // index of the point before the current time
+	seg   int // index of the segment for which corr was computed
+	corr  float64
+	osc   SineOsc
+}
+
+func NewSinusoidalSynth(a *SinusoidalAnalysis) *SinusoidalSynth {
+	sort.Slice(a.Tracks, func(i, j int) bool { return a.Tracks[i].Points[0].Time < a.Tracks[j].Points[0].Time })
+	return &SinusoidalSynth{analysis: a, stretch: 1, transpose: 1}
+}
+
+func (s *SinusoidalSynth) InitAudio(p Params) {
+	s.params = p
+	s.dt = 1 / p.SampleRate
+	for _, t := range s.active {
+		t.osc.InitAudio(p)
+	}
+}
+
+// Stretch sets the time stretch factor; 2 plays twice as slowly.
+func (s *SinusoidalSynth) Stretch(x float64) *SinusoidalSynth {
+	s.stretch = x
+	return s
+}
+
+// Transpose sets the frequency ratio by which all partials are shifted.
+func (s *SinusoidalSynth) Transpose(ratio float64) *SinusoidalSynth {
+	s.transpose = ratio
+	return s
+}
+
+// SetTime sets the playback position, in seconds of the analysis (i.e., before stretching).
+func (s *SinusoidalSynth) SetTime(t float64) {
+	s.t = t
+	s.next = 0
+	s.active = nil
+	s.startTracks()
+}
+
+func (s *SinusoidalSynth) startTracks() {
+	tracks := s.analysis.Tracks
+	for ; s.next < len(tracks) && tracks[s.next].Points[0].Time <= s.t; s.next++ {
+		tr := tracks[s.next]
+		if tr.Points[len(tr.Points)-1].Time <= s.t {
+			continue
+		}
+		r := &resynthTrack{track: tr, seg: -1}
+		r.osc.Exact(true) // so that phases follow the analysis
+		r.osc.InitAudio(s.params)
+		r.osc.Phase(tr.Points[0].Phase/(2*math.Pi) + .25) // SineOsc's phase 0 is a sine; analysis phases are of cosines
+		s.active = append(s.active, r)
+	}
+}
+
+func (s *SinusoidalSynth) Sing() float64 {
+	s.startTracks()
+	y := 0.0
+	for i, n := 0, len(s.active); i < n; {
+		r := s.active[i]
+		p := r.track.Points
+		for r.i+1 < len(p) && p[r.i+1].Time <= s.t {
+			r.i++
+		}
+		if r.i+1 >= len(p) {
+			n--
+			s.active[i] = s.active[n]
+			s.active[n] = nil
+			s.active = s.active[:n]
+			continue
+		}
+		p0, p1 := p[r.i], p[r.i+1]
+		x := (s.t - p0.Time) / (p1.Time - p0.Time)
+		freq := p0.Freq + x*(p1.Freq-p0.Freq)
+		amp := p0.Amplitude + x*(p1.Amplitude-p0.Amplitude)
+		if r.seg != r.i {
+			r.seg = r.i
+			r.corr = 0
+			if s.stretch == 1 && s.transpose == 1 {
+				// Offset the frequency slightly over this segment so that the phase at its end matches the analysis.
+				rem := p1.Time - s.t + s.dt
+				advance := math.Pi * (freq + p1.Freq) * rem
+				target := p1.Phase + math.Pi/2 - cmplx.Phase(r.osc.x)
+				r.corr = math.Remainder(target-advance, 2*math.Pi) / (2 * math.Pi * rem)
+			}
+		}
+		y += amp * r.osc.Freq((freq+r.corr)*s.transpose).Sing()
+		i++
+	}
+	s.t += s.dt / s.stretch
+	return y
+}
+
+func (s *SinusoidalSynth) Done() bool {
+	return s.next == len(s.analysis.Tracks) && len(s.active) == 0
+}
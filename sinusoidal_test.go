@@ -0,0 +1,60 @@
+package audio
+
+import (
+	"bytes"
+	"math"
+	"testing"
+)
+
+func TestSinusoidalAnalysis(t *testing.T) {
+	const sampleRate = 44100
+	x := make([]float64, sampleRate)
+	for i := range x {
+		t := float64(i) / sampleRate
+		x[i] = .5*math.Sin(2*math.Pi*440*t) + .25*math.Sin(2*math.Pi*1000*t+1)
+	}
+
+	a := AnalyzeSinusoids(x, sampleRate, AnalysisOptions{})
+	var long []*Track
+	for _, tr := range a.Tracks {
+		if len(tr.Points) > 50 {
+			long = append(long, tr)
+		}
+	}
+	if len(long) != 2 {
+		t.Fatalf("expected 2 long tracks, got %d", len(long))
+	}
+	for _, tr := range long {
+		p := tr.Points[len(tr.Points)/2]
+		freq, amp := 440.0, .5
+		if p.Freq > 700 {
+			freq, amp = 1000, .25
+		}
+		if math.Abs(p.Freq-freq) > 1 || math.Abs(p.Amplitude-amp) > .02 {
+			t.Errorf("expected %.f Hz at amplitude %.2f, got %.2f Hz at %.3f", freq, amp, p.Freq, p.Amplitude)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := a.Save(&buf); err != nil {
+		t.Fatal(err)
+	}
+	a, err := LoadSinusoidalAnalysis(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewSinusoidalSynth(a)
+	Init(s, Params{sampleRate})
+	errSum, sum := 0.0, 0.0
+	for i := 0; !s.Done(); i++ {
+		y := s.Sing()
+		if i > sampleRate/4 && i < 3*sampleRate/4 {
+			errSum += (y - x[i]) * (y - x[i])
+			sum += x[i] * x[i]
+		}
+	}
+	if snr := 10 * math.Log10(sum/errSum); snr < 20 {
+		t.Errorf("expected resynthesis SNR of at least 20 dB, got %.1f dB", snr)
+	}
+}
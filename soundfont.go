@@ -0,0 +1,362 @@
+package audio
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"strings"
+)
+
+// SoundFont is a SoundFont 2 file, with the zones of each preset's instruments resolved into SampleZones.
+type SoundFont struct {
+	Name    string
+	Presets []*SoundFontPreset
+}
+
+type SoundFontPreset struct {
+	Name          string
+	Bank, Program int
+	Zones         []*SampleZone
+}
+
+// Preset returns the preset with the given bank and program numbers, or nil if there is none.
+func (f *SoundFont) Preset(bank, program int) *SoundFontPreset {
+	for _, p := range f.Presets {
+		if p.Bank == bank && p.Program == program {
+			return p
+		}
+	}
+	return nil
+}
+
+// Sampler returns a Sampler that plays the preset.
+func (p *SoundFontPreset) Sampler() *Sampler {
+	return NewSampler(p.Zones...)
+}
+
+// ReadSoundFont reads a SoundFont 2 file.  The generators for sample addresses and loops, key and velocity
+// ranges, tuning, root key, attenuation, the volume envelope and the lowpass filter are supported; modulators
+// and other generators are ignored.
+func ReadSoundFont(r io.Reader) (*SoundFont, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	chunks, err := parseRIFF(b, "sfbk")
+	if err != nil {
+		return nil, err
+	}
+
+	f := &SoundFont{}
+	var smpl []byte
+	pdta := map[string][]byte{}
+	for _, c := range chunks {
+		if c.id != "LIST" || len(c.data) < 4 {
+			continue
+		}
+		sub, err := parseChunks(c.data[4:])
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range sub {
+			switch string(c.data[:4]) {
+			case "INFO":
+				if s.id == "INAM" {
+					f.Name = sf2String(s.data)
+				}
+			case "sdta":
+				if s.id == "smpl" {
+					smpl = s.data
+				}
+			case "pdta":
+				pdta[s.id] = s.data
+			}
+		}
+	}
+	for _, id := range []string{"phdr", "pbag", "pgen", "inst", "ibag", "igen", "shdr"} {
+		if _, ok := pdta[id]; !ok {
+			return nil, fmt.Errorf("soundfont: missing %s chunk", id)
+		}
+	}
+
+	samples := make([]float64, len(smpl)/2)
+	for i := range samples {
+		samples[i] = float64(int16(binary.LittleEndian.Uint16(smpl[2*i:]))) / (1 << 15)
+	}
+	headers, err := sf2SampleHeaders(pdta["shdr"])
+	if err != nil {
+		return nil, err
+	}
+	instZones, err := sf2Zones(pdta["inst"], false, pdta["ibag"], pdta["igen"])
+	if err != nil {
+		return nil, err
+	}
+	presetZones, err := sf2Zones(pdta["phdr"], true, pdta["pbag"], pdta["pgen"])
+	if err != nil {
+		return nil, err
+	}
+
+	phdr := pdta["phdr"]
+	for i, zones := range presetZones {
+		h := phdr[38*i:]
+		p := &SoundFontPreset{
+			Name:    sf2String(h[:20]),
+			Program: int(binary.LittleEndian.Uint16(h[20:])),
+			Bank:    int(binary.LittleEndian.Uint16(h[22:])),
+		}
+		for _, pz := range zones {
+			inst := pz[sf2Instrument]
+			if inst >= len(instZones) {
+				continue
+			}
+			for _, iz := range instZones[inst] {
+				id := iz[sf2SampleID]
+				if id >= len(headers) {
+					continue
+				}
+				z, err := sf2Zone(pz, iz, headers[id], samples)
+				if err != nil {
+					return nil, fmt.Errorf("soundfont: preset %q: %s", p.Name, err)
+				}
+				if z != nil {
+					p.Zones = append(p.Zones, z)
+				}
+			}
+		}
+		f.Presets = append(f.Presets, p)
+	}
+	return f, nil
+}
+
+func sf2String(b []byte) string {
+	if i := strings.IndexByte(string(b), 0); i >= 0 {
+		b = b[:i]
+	}
+	return string(b)
+}
+
+// Generator operators.
+const (
+	sf2StartOffset         = 0
+	sf2EndOffset           = 1
+	sf2LoopStartOffset     = 2
+	sf2LoopEndOffset       = 3
+	sf2StartCoarseOffset   = 4
+	sf2FilterCutoff        = 8
+	sf2FilterQ             = 9
+	sf2EndCoarseOffset     = 12
+	sf2DelayVolEnv         = 33
+	sf2AttackVolEnv        = 34
+	sf2HoldVolEnv          = 35
+	sf2DecayVolEnv         = 36
+	sf2SustainVolEnv       = 37
+	sf2ReleaseVolEnv       = 38
+	sf2Instrument          = 41
+	sf2KeyRange            = 43
+	sf2VelRange            = 44
+	sf2LoopStartCoarse     = 45
+	sf2Attenuation         = 48
+	sf2LoopEndCoarse       = 50
+	sf2CoarseTune          = 51
+	sf2FineTune            = 52
+	sf2SampleID            = 53
+	sf2SampleModes         = 54
+	sf2OverridingRootKey   = 58
+	sf2NumGenerators       = 61
+	sf2DefaultFilterCutoff = 13500
+)
+
+// sf2Generators holds the amounts of all generators of a zone.  Ranges are stored as lo | hi<<8.
+type sf2Generators [sf2NumGenerators]int
+
+var sf2Defaults = func() (g sf2Generators) {
+	g[sf2FilterCutoff] = sf2DefaultFilterCutoff
+	for _, i := range []int{sf2DelayVolEnv, sf2AttackVolEnv, sf2HoldVolEnv, sf2DecayVolEnv, sf2ReleaseVolEnv} {
+		g[i] = -12000
+	}
+	g[sf2KeyRange] = 127 << 8
+	g[sf2VelRange] = 127 << 8
+	g[sf2Instrument] = -1
+	g[sf2SampleID] = -1
+	g[sf2OverridingRootKey] = -1
+	return
+}()
+
+// sf2Zones reads the zones of each preset or instrument from its header records, bags and generators.
+// Generators of a global zone are applied to the other zones.  The terminal header is excluded.
+func sf2Zones(headers []byte, preset bool, bags, gens []byte) ([][]sf2Generators, error) {
+	// Instrument headers are 22 bytes, ending with the bag index.  Preset headers are 38 bytes, with the bag
+	// index at 24.
+	size, bagOffset, terminal := 22, 20, sf2SampleID
+	if preset {
+		size, bagOffset, terminal = 38, 24, sf2Instrument
+	}
+	n := len(headers)/size - 1
+	if n < 0 {
+		return nil, errors.New("soundfont: missing terminal record")
+	}
+	bagIndex := func(i int) int { return int(binary.LittleEndian.Uint16(headers[size*i+bagOffset:])) }
+	genIndex := func(i int) int { return int(binary.LittleEndian.Uint16(bags[4*i:])) }
+
+	all := make([][]sf2Generators, n)
+	for i := range all {
+		b0, b1 := bagIndex(i), bagIndex(i+1)
+		if b0 > b1 || 4*b1+4 > len(bags) {
+			return nil, errors.New("soundfont: bad bag index")
+		}
+		global := sf2Defaults
+		if preset {
+			global = sf2Generators{} // preset generators are offsets
+			global[sf2KeyRange] = 127 << 8
+			global[sf2VelRange] = 127 << 8
+		}
+		for b := b0; b < b1; b++ {
+			g0, g1 := genIndex(b), genIndex(b+1)
+			if g0 > g1 || 4*g1 > len(gens) {
+				return nil, errors.New("soundfont: bad generator index")
+			}
+			z := global
+			hasTerminal := false
+			for g := g0; g < g1; g++ {
+				op := int(binary.LittleEndian.Uint16(gens[4*g:]))
+				if op >= sf2NumGenerators {
+					continue
+				}
+				amount := int(int16(binary.LittleEndian.Uint16(gens[4*g+2:])))
+				if op == sf2KeyRange || op == sf2VelRange {
+					amount = int(gens[4*g+2]) | int(gens[4*g+3])<<8
+				} else if op == sf2Instrument || op == sf2SampleID {
+					amount = int(binary.LittleEndian.Uint16(gens[4*g+2:]))
+				}
+				z[op] = amount
+				hasTerminal = hasTerminal || op == terminal
+			}
+			if !hasTerminal {
+				if b == b0 {
+					global = z
+				}
+				continue
+			}
+			all[i] = append(all[i], z)
+		}
+	}
+	return all, nil
+}
+
+type sf2SampleHeader struct {
+	start, end, loopStart, loopEnd int
+	sampleRate                     float64
+	pitch                          int
+	correction                     int
+}
+
+func sf2SampleHeaders(b []byte) ([]sf2SampleHeader, error) {
+	if len(b) < 46 {
+		return nil, errors.New("soundfont: missing terminal sample header")
+	}
+	h := make([]sf2SampleHeader, len(b)/46-1)
+	for i := range h {
+		r := b[46*i:]
+		u32 := func(j int) int { return int(binary.LittleEndian.Uint32(r[j:])) }
+		h[i] = sf2SampleHeader{
+			start:      u32(20),
+			end:        u32(24),
+			loopStart:  u32(28),
+			loopEnd:    u32(32),
+			sampleRate: float64(u32(36)),
+			pitch:      int(r[40]),
+			correction: int(int8(r[41])),
+		}
+	}
+	return h, nil
+}
+
+// sf2Zone combines a preset zone and an instrument zone into a SampleZone, or returns nil if their key or
+// velocity ranges do not overlap.
+func sf2Zone(pz, iz sf2Generators, h sf2SampleHeader, samples []float64) (*SampleZone, error) {
+	loKey, hiKey := sf2Intersect(pz[sf2KeyRange], iz[sf2KeyRange])
+	loVel, hiVel := sf2Intersect(pz[sf2VelRange], iz[sf2VelRange])
+	if loKey > hiKey || loVel > hiVel {
+		return nil, nil
+	}
+	g := iz
+	for op := range g {
+		switch op {
+		case sf2KeyRange, sf2VelRange, sf2Instrument, sf2SampleID, sf2SampleModes, sf2OverridingRootKey,
+			sf2StartOffset, sf2EndOffset, sf2LoopStartOffset, sf2LoopEndOffset,
+			sf2StartCoarseOffset, sf2EndCoarseOffset, sf2LoopStartCoarse, sf2LoopEndCoarse:
+			// not allowed at the preset level
+		default:
+			g[op] += pz[op]
+		}
+	}
+
+	start := h.start + g[sf2StartOffset] + 32768*g[sf2StartCoarseOffset]
+	end := h.end + g[sf2EndOffset] + 32768*g[sf2EndCoarseOffset]
+	if start < 0 || end > len(samples) || start >= end {
+		return nil, fmt.Errorf("bad sample addresses %d-%d", start, end)
+	}
+	if h.sampleRate <= 0 {
+		return nil, errors.New("bad sample rate")
+	}
+	root := h.pitch
+	if root > 127 {
+		root = 60
+	}
+	if g[sf2OverridingRootKey] >= 0 {
+		root = g[sf2OverridingRootKey]
+	}
+	timecents := func(op int) float64 {
+		if g[op] <= -12000 {
+			return 0
+		}
+		return math.Exp2(float64(g[op]) / 1200)
+	}
+
+	z := &SampleZone{
+		Sample:      samples[start:end],
+		SampleRate:  h.sampleRate,
+		Root:        float64(root),
+		Tune:        float64(100*g[sf2CoarseTune] + g[sf2FineTune] + h.correction),
+		LoKey:       loKey,
+		HiKey:       hiKey,
+		LoVel:       float64(loVel) / 127,
+		HiVel:       float64(hiVel) / 127,
+		Attenuation: float64(g[sf2Attenuation]) / 10,
+		LoopStart:   h.loopStart + g[sf2LoopStartOffset] + 32768*g[sf2LoopStartCoarse] - start,
+		LoopEnd:     h.loopEnd + g[sf2LoopEndOffset] + 32768*g[sf2LoopEndCoarse] - start,
+		Envelope: &ZoneEnvelope{
+			Delay:   timecents(sf2DelayVolEnv),
+			Attack:  timecents(sf2AttackVolEnv),
+			Hold:    timecents(sf2HoldVolEnv),
+			Decay:   timecents(sf2DecayVolEnv),
+			Sustain: math.Pow(10, -float64(g[sf2SustainVolEnv])/200),
+			Release: timecents(sf2ReleaseVolEnv),
+		},
+	}
+	switch g[sf2SampleModes] & 3 {
+	case 1:
+		z.Loop = LoopContinuous
+	case 3:
+		z.Loop = LoopSustain
+	}
+	if fc := g[sf2FilterCutoff]; fc < sf2DefaultFilterCutoff {
+		z.Cutoff = 8.176 * math.Exp2(float64(fc)/1200)
+		z.Resonance = float64(g[sf2FilterQ]) / 10
+	}
+	return z, nil
+}
+
+func sf2Intersect(a, b int) (lo, hi int) {
+	lo, hi = a&0xFF, a>>8
+	if l := b & 0xFF; l > lo {
+		lo = l
+	}
+	if h := b >> 8; h < hi {
+		hi = h
+	}
+	return
+}
@@ -0,0 +1,113 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+)
+
+func TestReadSoundFont(t *testing.T) {
+	sf2, err := ReadSoundFont(bytes.NewReader(testSoundFont()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sf2.Name != "Test" || len(sf2.Presets) != 1 {
+		t.Fatalf("expected one preset in a font named Test, got %q with %d", sf2.Name, len(sf2.Presets))
+	}
+	p := sf2.Preset(0, 5)
+	if p == nil || p.Name != "Sine" || len(p.Zones) != 1 {
+		t.Fatalf("expected preset Sine with one zone, got %+v", p)
+	}
+	z := p.Zones[0]
+	if z.Root != 57 || z.LoKey != 0 || z.HiKey != 100 || z.Loop != LoopSustain || z.LoopStart != 100 || z.LoopEnd != 4100 ||
+		z.Attenuation != 6 || math.Abs(z.Envelope.Attack-.01) > 1e-4 || z.Cutoff == 0 {
+		t.Errorf("wrong zone: root %v, keys %d-%d, loop %v %d-%d, attenuation %v, envelope %+v, cutoff %v",
+			z.Root, z.LoKey, z.HiKey, z.Loop, z.LoopStart, z.LoopEnd, z.Attenuation, *z.Envelope, z.Cutoff)
+	}
+
+	const sampleRate = 48000
+	s := p.Sampler()
+	Init(s, Params{sampleRate})
+	s.Play(SamplerNote{
+		Pitch:    []*ControlPoint{{Time: 0, Value: MIDINotePitch(81)}},
+		Velocity: []*ControlPoint{{Time: 0, Value: 1}},
+		Duration: []*ControlPoint{{Time: 0, Value: 1}},
+	})
+	x := make([]float64, sampleRate)
+	for i := range x {
+		x[i] = s.Sing()
+	}
+	if f := estimateFreq(x[sampleRate/2:], sampleRate, 882); math.Abs(f/882-1) > .001 {
+		t.Errorf("expected frequency 882, got %f", f)
+	}
+}
+
+// testSoundFont builds a SoundFont with a looped 441 Hz sine, played an octave up (key 69) by preset 5.
+func testSoundFont() []byte {
+	chunk := func(id string, data []byte) []byte {
+		var b bytes.Buffer
+		b.WriteString(id)
+		binary.Write(&b, binary.LittleEndian, uint32(len(data)))
+		b.Write(data)
+		if len(data)%2 == 1 {
+			b.WriteByte(0)
+		}
+		return b.Bytes()
+	}
+	list := func(typ string, chunks ...[]byte) []byte {
+		return chunk("LIST", append([]byte(typ), bytes.Join(chunks, nil)...))
+	}
+	records := func(fields ...interface{}) []byte {
+		var b bytes.Buffer
+		for _, f := range fields {
+			if s, ok := f.(string); ok {
+				name := make([]byte, 20)
+				copy(name, s)
+				b.Write(name)
+				continue
+			}
+			binary.Write(&b, binary.LittleEndian, f)
+		}
+		return b.Bytes()
+	}
+	gen := func(op uint16, amount interface{}) []byte { return records(op, amount) }
+	u16 := func(x int) uint16 { return uint16(x) }
+	u32 := func(x int) uint32 { return uint32(x) }
+
+	const n = 4410
+	var smpl bytes.Buffer
+	for i := 0; i < n+46; i++ {
+		x := 0.0
+		if i < n {
+			x = .5 * math.Sin(2*math.Pi*441*float64(i)/44100)
+		}
+		binary.Write(&smpl, binary.LittleEndian, int16(x*32767))
+	}
+
+	phdr := append(records("Sine", u16(5), u16(0), u16(0), u32(0), u32(0), u32(0)),
+		records("EOP", u16(0), u16(0), u16(1), u32(0), u32(0), u32(0))...)
+	pbag := records(u16(0), u16(0), u16(2), u16(0))
+	pgen := append(append(gen(sf2KeyRange, [2]uint8{0, 100}), gen(sf2Instrument, u16(0))...), gen(0, u16(0))...)
+	inst := append(records("Sine", u16(0)), records("EOI", u16(2))...)
+	ibag := records(u16(0), u16(0), u16(1), u16(0), u16(5), u16(0))
+	igen := bytes.Join([][]byte{
+		gen(sf2AttackVolEnv, int16(-7972)), // global: 10 ms
+		gen(sf2Attenuation, int16(60)),
+		gen(sf2FilterCutoff, int16(12000)),
+		gen(sf2SampleModes, int16(3)),
+		gen(sf2SampleID, u16(0)),
+		gen(0, u16(0)),
+	}, nil)
+	shdr := append(records("sine", u32(0), u32(n), u32(100), u32(4100), u32(44100), uint8(57), int8(0), u16(0), u16(1)),
+		records("EOS", u32(0), u32(0), u32(0), u32(0), u32(0), uint8(0), int8(0), u16(0), u16(0))...)
+
+	body := bytes.Join([][]byte{
+		[]byte("sfbk"),
+		list("INFO", chunk("ifil", []byte{2, 0, 1, 0}), chunk("INAM", []byte("Test\x00"))),
+		list("sdta", chunk("smpl", smpl.Bytes())),
+		list("pdta", chunk("phdr", phdr), chunk("pbag", pbag), chunk("pmod", make([]byte, 10)), chunk("pgen", pgen),
+			chunk("inst", inst), chunk("ibag", ibag), chunk("imod", make([]byte, 10)), chunk("igen", igen), chunk("shdr", shdr)),
+	}, nil)
+	return chunk("RIFF", body)
+}
@@ -0,0 +1,246 @@
+package audio
+
+import "math"
+
+// Vec3 is a point or direction in 3D space, in meters.  X points right, Y points forward and Z points up.
+type Vec3 struct {
+	X, Y, Z float64
+}
+
+func (v Vec3) Add(u Vec3) Vec3         { return Vec3{v.X + u.X, v.Y + u.Y, v.Z + u.Z} }
+func (v Vec3) Sub(u Vec3) Vec3         { return Vec3{v.X - u.X, v.Y - u.Y, v.Z - u.Z} }
+func (v Vec3) Scale(a float64) Vec3    { return Vec3{a * v.X, a * v.Y, a * v.Z} }
+func (v Vec3) Dot(u Vec3) float64      { return v.X*u.X + v.Y*u.Y + v.Z*u.Z }
+func (v Vec3) Len() float64            { return math.Sqrt(v.Dot(v)) }
+func (v Vec3) Distance(u Vec3) float64 { return v.Sub(u).Len() }
+
+// Position makes a fixed Vec3 usable as a Path.
+func (v Vec3) Position() Vec3 { return v }
+
+// A Path is the trajectory of a sound source or listener.  Position is called once per sample.
+type Path interface {
+	Position() Vec3
+}
+
+// ControlPath is a Path whose coordinates are automated by Controls.
+type ControlPath struct {
+	X, Y, Z Control
+}
+
+func (p *ControlPath) Position() Vec3 {
+	return Vec3{p.X.Sing(), p.Y.Sing(), p.Z.Sing()}
+}
+
+// FuncPath is a Path given as a function of time in seconds.
+type FuncPath struct {
+	f     func(t float64) Vec3
+	t, dt float64
+}
+
+func NewFuncPath(f func(t float64) Vec3) *FuncPath {
+	return &FuncPath{f: f}
+}
+
+func (p *FuncPath) InitAudio(params Params) {
+	p.dt = 1 / params.SampleRate
+}
+
+func (p *FuncPath) Position() Vec3 {
+	x := p.f(p.t)
+	p.t += p.dt
+	return x
+}
+
+const (
+	SpeedOfSound = 343.0
+	earDistance  = .175
+)
+
+// Space renders mono Voices moving through 3D space as heard at a set of receivers (ears, microphones or speakers)
+// placed relative to a listener.  Each receiver hears every source through a propagation delay, so that movement
+// produces a Doppler shift, attenuated with distance and lowpass filtered to model absorption by the air.
+type Space struct {
+	Params Params
+
+	// Listener is the position of the listener; nil means the origin.
+	Listener Path
+	// Receivers are the positions of the output channels relative to Listener.
+	Receivers []Vec3
+
+	SpeedOfSound float64 // meters per second
+	RefDistance  float64 // distance (meters) below which there is no attenuation
+	// AirAbsorption is the attenuation (dB per meter per Hz²) at high frequencies.
+	// It sets the cutoff of the air absorption lowpass:  sqrt(3 / (AirAbsorption * distance)).
+	AirAbsorption float64
+
+	sources []*spaceSource
+	out     []float64
+}
+
+// NewSpace returns a Space with the given receivers and default physical constants.
+func NewSpace(receivers ...Vec3) *Space {
+	return &Space{
+		Receivers:     receivers,
+		SpeedOfSound:  SpeedOfSound,
+		RefDistance:   1,
+		AirAbsorption: 1e-9,
+	}
+}
+
+// NewStereoSpace returns a Space whose receivers are a pair of ears.
+func NewStereoSpace() *Space {
+	return NewSpace(Vec3{X: -earDistance / 2}, Vec3{X: earDistance / 2})
+}
+
+type spaceSource struct {
+	voice   Voice
+	path    Path
+	delay   Delay
+	lp      []LowPass1
+	pos     Vec3
+	started bool
+	done    bool
+	tail    int
+}
+
+func (s *Space) InitAudio(p Params) {
+	s.Params = p
+	if s.Listener != nil {
+		Init(s.Listener, p)
+	}
+	for _, src := range s.sources {
+		Init(src.voice, p)
+		Init(src.path, p)
+		src.delay.Params = p
+		Init(src.lp, p)
+	}
+}
+
+// Add places v in the Space, moving along path.
+func (s *Space) Add(v Voice, path Path) {
+	Init(v, s.Params)
+	Init(path, s.Params)
+	src := &spaceSource{voice: v, path: path, lp: make([]LowPass1, len(s.Receivers))}
+	src.delay.Params = s.Params
+	for i := range src.lp {
+		src.lp[i].p = s.Params
+		src.lp[i].Freq(s.Params.SampleRate / 2)
+	}
+	s.sources = append(s.sources, src)
+}
+
+// SingChannels renders one sample for each receiver into out, which must have length len(s.Receivers).
+func (s *Space) SingChannels(out []float64) {
+	for i := range out {
+		out[i] = 0
+	}
+	listener := Vec3{}
+	if s.Listener != nil {
+		listener = s.Listener.Position()
+	}
+	for i, n := 0, len(s.sources); i < n; {
+		src := s.sources[i]
+		pos := src.path.Position()
+		vel := Vec3{}
+		if src.started {
+			vel = pos.Sub(src.pos).Scale(s.Params.SampleRate)
+		}
+		src.pos = pos
+		src.started = true
+
+		maxDelay := 0.0
+		for j, r := range s.Receivers {
+			y := 0.0
+			d := 0.0
+			delays, nDelays := s.propagationDelays(pos.Sub(listener.Add(r)), vel)
+			for _, t := range delays[:nDelays] {
+				maxDelay = math.Max(maxDelay, t)
+				d = t * s.SpeedOfSound
+				y += src.delay.Read(math.Max(2/s.Params.SampleRate, t)) * s.gain(d)
+			}
+			src.lp[j].Freq(math.Min(s.Params.SampleRate/2, math.Sqrt(3/(s.AirAbsorption*d))))
+			out[j] += src.lp[j].Filter(y)
+		}
+
+		x := 0.0
+		if !src.done {
+			x = src.voice.Sing()
+			if src.voice.Done() {
+				src.done = true
+				src.tail = int(maxDelay*s.Params.SampleRate) + 4
+			}
+		} else {
+			src.tail--
+		}
+		src.delay.Write(x)
+
+		if src.done && src.tail <= 0 {
+			n--
+			s.sources[i] = s.sources[n]
+			s.sources[n] = nil
+			s.sources = s.sources[:n]
+		} else {
+			i++
+		}
+	}
+}
+
+// propagationDelays returns the times t>0 at which sound arriving now at a receiver was emitted by a source at
+// relative position a moving at constant velocity v, i.e., the solutions of |a - v*t| = c*t.
+// There is exactly one solution for a subsonic source.  A supersonic source is heard not at all while it
+// outruns its sound and then twice (the sonic boom followed by a time-reversed image).
+func (s *Space) propagationDelays(a, v Vec3) (t [2]float64, n int) {
+	c := s.SpeedOfSound
+	A := v.Dot(v) - c*c
+	B := -2 * a.Dot(v)
+	C := a.Dot(a)
+	if C == 0 {
+		return t, 1
+	}
+	if math.Abs(A) < 1e-9 {
+		if B < 0 {
+			t[0] = -C / B
+			return t, 1
+		}
+		return t, 0
+	}
+	disc := B*B - 4*A*C
+	if disc < 0 {
+		return t, 0
+	}
+	sq := math.Sqrt(disc)
+	t[0], t[1] = (-B-sq)/(2*A), (-B+sq)/(2*A)
+	if A < 0 {
+		return t, 1
+	}
+	if t[1] > 0 {
+		return t, 2
+	}
+	return t, 0
+}
+
+func (s *Space) gain(d float64) float64 {
+	if d <= s.RefDistance {
+		return 1
+	}
+	return s.RefDistance / d
+}
+
+// Sing renders the first two receivers, making a stereo Space a StereoVoice.
+func (s *Space) Sing() (float64, float64) {
+	if len(s.out) != len(s.Receivers) {
+		s.out = make([]float64, len(s.Receivers))
+	}
+	s.SingChannels(s.out)
+	switch len(s.out) {
+	case 0:
+		return 0, 0
+	case 1:
+		return s.out[0], s.out[0]
+	}
+	return s.out[0], s.out[1]
+}
+
+func (s *Space) Done() bool {
+	return len(s.sources) == 0
+}
@@ -0,0 +1,135 @@
+package audio
+
+import (
+	"math"
+	"math/cmplx"
+	"testing"
+)
+
+func TestSpace_Doppler(t *testing.T) {
+	const (
+		sampleRate = 48000
+		freq       = 1000
+		speed      = SpeedOfSound / 10
+	)
+
+	s := NewSpace(Vec3{})
+	Init(s, Params{sampleRate})
+	s.Add(&endlessSine{*new(SineOsc).Freq(freq)}, NewFuncPath(func(t float64) Vec3 { return Vec3{Y: 10 + speed*t} }))
+
+	out := make([]float64, 1)
+	for i := 0; i < sampleRate; i++ {
+		s.SingChannels(out)
+	}
+	crossings := 0
+	prev := 0.0
+	for i := 0; i < sampleRate; i++ {
+		s.SingChannels(out)
+		if prev < 0 && out[0] >= 0 {
+			crossings++
+		}
+		prev = out[0]
+	}
+	expected := freq * SpeedOfSound / (SpeedOfSound + speed)
+	if math.Abs(float64(crossings)-expected) > 2 {
+		t.Errorf("expected frequency %.1f, got %d", expected, crossings)
+	}
+}
+
+type endlessSine struct{ SineOsc }
+
+func (endlessSine) Done() bool { return false }
+
+func TestSpace_Supersonic(t *testing.T) {
+	const (
+		sampleRate = 48000
+		y          = 50.0
+		speed      = 2 * SpeedOfSound
+	)
+	// The Mach cone (half-angle asin(1/2)) reaches the listener when the source has passed it by y*cot(30°).
+	boomX := y * math.Sqrt(3)
+	x0 := -500.0
+	boom := (boomX - x0) / speed
+
+	s := NewSpace(Vec3{})
+	for _, a := range []Vec3{{X: -100, Y: y}, {X: 0, Y: y}, {X: boomX - 1, Y: y}} {
+		if _, n := s.propagationDelays(a, Vec3{X: speed}); n != 0 {
+			t.Errorf("source at %v:  expected silence, got %d arrivals", a, n)
+		}
+	}
+	for _, a := range []Vec3{{X: boomX + 1, Y: y}, {X: 500, Y: y}} {
+		delays, n := s.propagationDelays(a, Vec3{X: speed})
+		if n != 2 {
+			t.Errorf("source at %v:  expected 2 arrivals, got %d", a, n)
+			continue
+		}
+		for _, d := range delays {
+			// The sound emitted d seconds ago travelled d*c.
+			if dist := a.Sub(Vec3{X: speed * d}).Len(); d <= 0 || math.Abs(dist-d*SpeedOfSound) > 1e-6 {
+				t.Errorf("source at %v:  arrival %v s ago is inconsistent", a, d)
+			}
+		}
+	}
+
+	Init(s, Params{sampleRate})
+	s.Add(&endlessSine{*new(SineOsc).Freq(200)}, NewFuncPath(func(t float64) Vec3 { return Vec3{X: x0 + speed*t, Y: y} }))
+	out := make([]float64, 1)
+	var peakBefore, peakAfter float64
+	for i := 0; i < 2*sampleRate; i++ {
+		s.SingChannels(out)
+		switch tm := float64(i) / sampleRate; {
+		case tm < boom-.01:
+			peakBefore = math.Max(peakBefore, math.Abs(out[0]))
+		case tm < boom+.05:
+			peakAfter = math.Max(peakAfter, math.Abs(out[0]))
+		}
+	}
+	if peakBefore != 0 {
+		t.Errorf("expected silence before the boom at %.3f s, got peak %g", boom, peakBefore)
+	}
+	// Each image alone is attenuated to at most 1/y; at the boom they arrive together.
+	if peakAfter < 1.5/y {
+		t.Errorf("expected a boom louder than either image alone, got peak %g", peakAfter)
+	}
+}
+
+// spaceTone returns the steady-state amplitude of a sine at freq from a static source at distance d.
+func spaceTone(s *Space, freq, d float64) float64 {
+	const sampleRate = 48000
+	s.sources = nil
+	Init(s, Params{sampleRate})
+	s.Add(&endlessSine{*new(SineOsc).Freq(freq)}, Vec3{Y: d})
+	out := make([]float64, 1)
+	peak := 0.0
+	for i := 0; i < 2*sampleRate; i++ {
+		s.SingChannels(out)
+		if i > 3*sampleRate/2 {
+			peak = math.Max(peak, math.Abs(out[0]))
+		}
+	}
+	return peak
+}
+
+func TestSpace_Attenuation(t *testing.T) {
+	s := NewSpace(Vec3{})
+	s.AirAbsorption = 0
+	for _, c := range []struct{ d, gain float64 }{{.5, 1}, {1, 1}, {10, .1}, {40, .025}} {
+		if a := spaceTone(s, 100, c.d); math.Abs(a/c.gain-1) > .01 {
+			t.Errorf("distance %v:  expected gain %v, got %v", c.d, c.gain, a)
+		}
+	}
+}
+
+func TestSpace_AirAbsorption(t *testing.T) {
+	const sampleRate = 48000
+	s := NewSpace(Vec3{})
+	for _, d := range []float64{10, 100, 300} {
+		cutoff := math.Sqrt(3 / (s.AirAbsorption * d))
+		// the response of LowPass1 at 5 kHz
+		b1 := math.Exp(-2 * math.Pi * math.Min(cutoff, sampleRate/2) / sampleRate)
+		want := (1 - b1) / cmplx.Abs(1-complex(b1, 0)*cmplx.Exp(complex(0, -2*math.Pi*5000/sampleRate)))
+		if a := spaceTone(s, 5000, d) * d; math.Abs(a/want-1) > .02 {
+			t.Errorf("distance %v (cutoff %.0f Hz):  expected gain %v at 5 kHz, got %v", d, cutoff, want, a)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package audio
+
+import (
+	"math"
+	"math/cmplx"
+)
+
+// WaveguideString is an Instrument modelling a vibrating string as a digital waveguide (an extended
+// Karplus-Strong string).  The string is split at the excitation point into two delay lines, one towards the
+// bridge and one towards the nut, so that it can be plucked or bowed anywhere along its length.  The loop is
+// tuned with fractional delays, with the delays of its loss and dispersion filters taken into account.
+type WaveguideString struct {
+	Params Params
+	// Tension is the change in tension relative to that at which the strings are tuned.  Frequency is
+	// proportional to the square root of tension, so a Tension of .21 raises all notes by about 10%.
+	Tension Control
+	// Damping is added loss, in dB per second (e.g., a palm resting on the strings).
+	Damping Control
+	// Decay is the time in seconds in which the fundamental of an undamped string decays by 60 dB.
+	Decay float64
+	// Brightness (0..1) controls the loss of high frequencies relative to low ones.  At 1, all partials
+	// decay at the same rate.
+	Brightness float64
+	// Stiffness (0..1) makes the string dispersive, sharpening its upper partials as in a piano string.
+	Stiffness float64
+	// Release is the time in seconds in which a string is muted when its note ends.
+	Release float64
+
+	tension, damping float64
+	voices           MultiVoice
+}
+
+// PluckNote is a note plucked at Position (0..1, from the bridge to the nut).  Pitch is log2 of the
+// frequency at zero Tension (see MIDINotePitch).  The string rings until the end of the note's controls and
+// is then muted.
+type PluckNote struct {
+	Pitch, Amplitude, Position []*ControlPoint
+}
+
+// BowNote is a note bowed at Position (0..1, from the bridge to the nut) with the given bow Velocity
+// (typically up to about .3) and Force (0..1).  Pitch is log2 of the frequency at zero Tension.  At the end
+// of the note's controls the bow is lifted and the string is muted.
+type BowNote struct {
+	Pitch, Velocity, Force, Position []*ControlPoint
+}
+
+func NewWaveguideString() *WaveguideString {
+	return &WaveguideString{Decay: 3, Brightness: .5, Release: .1}
+}
+
+func (s *WaveguideString) InitAudio(p Params) {
+	s.Params = p
+	s.Tension.InitAudio(p)
+	s.Damping.InitAudio(p)
+	s.voices.Params = p
+	Init(s.voices.Voices, p)
+}
+
+// Play plucks a string.  It lets a WaveguideString be played by a PatternPlayer.
+func (s *WaveguideString) Play(n PluckNote) {
+	s.Pluck(n)
+}
+
+func (s *WaveguideString) Pluck(n PluckNote) {
+	v := &stringVoice{synth: s, plucked: true}
+	v.pitch.points = n.Pitch
+	v.amp.points = n.Amplitude
+	v.pos.points = n.Position
+	s.voices.Add(v)
+}
+
+func (s *WaveguideString) Bow(n BowNote) {
+	v := &stringVoice{synth: s, bowed: true}
+	v.pitch.points = n.Pitch
+	v.vel.points = n.Velocity
+	v.force.points = n.Force
+	v.pos.points = n.Position
+	v.amp.points = []*ControlPoint{{Time: 0, Value: 1}}
+	s.voices.Add(v)
+}
+
+// Mute damps all sounding strings so that they fall silent (by 60 dB) in t seconds.
+func (s *WaveguideString) Mute(t float64) {
+	for _, v := range s.voices.Voices {
+		v.(*stringVoice).mute(t)
+	}
+}
+
+func (s *WaveguideString) Sing() float64 {
+	s.tension = s.Tension.Sing()
+	s.damping = s.Damping.Sing()
+	return s.voices.Sing()
+}
+
+func (s *WaveguideString) Done() bool {
+	return s.voices.Done()
+}
+
+func (s *WaveguideString) Stop() {
+	s.voices.Stop()
+}
+
+// stringDispersionSections is the number of first-order allpass filters that make a stiff string dispersive.
+const stringDispersionSections = 4
+
+// stringPluckTime is the duration in seconds of the pulse with which a string is plucked.
+const stringPluckTime = .001
+
+type stringVoice struct {
+	synth                       *WaveguideString
+	sr                          float64
+	pitch, amp, pos, vel, force Control
+	bowed                       bool
+	plucked                     bool
+	pluck, pluckLen             int // progress through the pluck excitation
+	neck, bridge                Delay
+	lp                          float64 // loss filter state
+	ap                          [stringDispersionSections]struct{ x1, y1 float64 }
+	released                    bool
+	muteRate                    float64 // dB per second
+	level, levelDecay           float64
+}
+
+func (v *stringVoice) InitAudio(p Params) {
+	v.sr = p.SampleRate
+	Init(&v.pitch, p)
+	Init(&v.amp, p)
+	Init(&v.pos, p)
+	Init(&v.vel, p)
+	Init(&v.force, p)
+	Init(&v.neck, p)
+	Init(&v.bridge, p)
+	v.levelDecay = math.Exp(-1 / (.02 * p.SampleRate))
+	v.pluckLen = int(stringPluckTime*p.SampleRate) + 1
+}
+
+func (v *stringVoice) mute(t float64) {
+	v.muteRate = math.Max(v.muteRate, 60/t)
+}
+
+func (v *stringVoice) Sing() float64 {
+	s := v.synth
+	freq := math.Exp2(v.pitch.Sing()) * math.Sqrt(math.Max(0, 1+s.tension))
+	amp := v.amp.Sing()
+	pos := v.pos.Sing()
+	vel := v.vel.Sing()
+	force := v.force.Sing()
+	if !v.released && v.pitch.Done() && v.amp.Done() && v.pos.Done() && v.vel.Done() && v.force.Done() {
+		v.released = true
+		v.bowed = false
+		v.mute(s.Release)
+	}
+
+	// Split the period between the filters and the two delay lines.
+	freq = math.Min(freq, v.sr/8)
+	w := 2 * math.Pi * freq / v.sr
+	z := cmplx.Exp(complex(0, -w))
+	b := .6 * (1 - math.Max(0, math.Min(1, s.Brightness)))
+	lp := complex(1-b, 0) / (1 - complex(b, 0)*z)
+	a := -.7 * math.Max(0, math.Min(1, s.Stiffness))
+	ap := (complex(a, 0) + z) / (1 + complex(a, 0)*z)
+	period := v.sr/freq + (cmplx.Phase(lp)+stringDispersionSections*cmplx.Phase(ap))/w
+	period = math.Max(period, 4)
+	nb := math.Max(2, math.Min(period-2, pos*period))
+	nn := period - nb
+
+	// Loss per period, such that the fundamental decays at the given rate.
+	rate := s.damping + v.muteRate
+	if s.Decay > 0 {
+		rate += 60 / s.Decay
+	}
+	g := math.Min(math.Pow(10, -rate/20/freq)/cmplx.Abs(lp), 1)
+
+	bridgeOut := v.bridge.Read(nb / v.sr)
+	nutOut := v.neck.Read(nn / v.sr)
+
+	v.lp = (1-b)*g*bridgeOut + b*v.lp
+	x := v.lp
+	for i := range v.ap {
+		f := &v.ap[i]
+		y := a*x + f.x1 - a*f.y1
+		f.x1, f.y1 = x, y
+		x = y
+	}
+	bridgeRefl := -x
+	nutRefl := -nutOut
+
+	excitation := 0.0
+	if v.plucked && v.pluck < v.pluckLen {
+		v.pluck++
+		excitation = (1 - math.Cos(2*math.Pi*float64(v.pluck)/float64(v.pluckLen+1))) / 2
+	}
+	if v.bowed {
+		d := vel - (bridgeRefl + nutRefl)
+		excitation += d * bowFriction(d, force)
+	}
+	v.neck.Write(bridgeRefl + excitation)
+	v.bridge.Write(nutRefl + excitation)
+
+	y := amp * bridgeOut
+	v.level = math.Max(math.Abs(y), v.level*v.levelDecay)
+	return y
+}
+
+// bowFriction is the reflection coefficient of the bow-string junction for a given velocity difference,
+// as in Smith's bowed string model.  Greater force gives a wider region of sticking.
+func bowFriction(dv, force float64) float64 {
+	slope := 5 - 4*math.Max(0, math.Min(1, force))
+	return math.Min(math.Pow(math.Abs(dv*slope)+.75, -4), 1)
+}
+
+func (v *stringVoice) Done() bool {
+	return v.released && v.level < 1e-4
+}
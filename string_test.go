@@ -0,0 +1,60 @@
+package audio
+
+import (
+	"math"
+	"testing"
+)
+
+func TestWaveguideString(t *testing.T) {
+	const sampleRate = 48000
+	for _, c := range []struct {
+		name    string
+		tension float64
+		bow     bool
+	}{
+		{"pluck", 0, false},
+		{"tension", .21, false},
+		{"bow", 0, true},
+	} {
+		s := NewWaveguideString()
+		Init(s, Params{sampleRate})
+		s.Tension.SetPoints([]*ControlPoint{{Time: 0, Value: c.tension}})
+		pitch := []*ControlPoint{{Time: 0, Value: math.Log2(220)}, {Time: 1, Value: math.Log2(220)}}
+		if c.bow {
+			s.Bow(BowNote{
+				Pitch:    pitch,
+				Velocity: []*ControlPoint{{Time: 0, Value: .2}, {Time: 1, Value: .2}},
+				Force:    []*ControlPoint{{Time: 0, Value: .5}},
+				Position: []*ControlPoint{{Time: 0, Value: .13}},
+			})
+		} else {
+			s.Pluck(PluckNote{
+				Pitch:     pitch,
+				Amplitude: []*ControlPoint{{Time: 0, Value: 1}},
+				Position:  []*ControlPoint{{Time: 0, Value: .13}},
+			})
+		}
+		x := make([]float64, sampleRate)
+		for i := range x {
+			x[i] = s.Sing()
+		}
+
+		want := 220 * math.Sqrt(1+c.tension)
+		if f := estimateFreq(x[sampleRate/2:], sampleRate, want); math.Abs(f/want-1) > .002 {
+			t.Errorf("%s: expected frequency %.1f, got %.1f", c.name, want, f)
+		}
+		if c.bow {
+			if early, late := rms(x[sampleRate/4:sampleRate/2]), rms(x[3*sampleRate/4:]); early < .01 || late < .8*early {
+				t.Errorf("%s: expected sustained oscillation, got rms %f then %f", c.name, early, late)
+			}
+		}
+
+		n := 0
+		for ; !s.Done() && n < sampleRate; n++ {
+			s.Sing()
+		}
+		if n == sampleRate {
+			t.Errorf("%s: string was not muted after its note ended", c.name)
+		}
+	}
+}
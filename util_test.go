@@ -0,0 +1,31 @@
+package audio
+
+import "math"
+
+// estimateFreq finds the frequency near approx at which x is most self-similar.
+func estimateFreq(x []float64, sampleRate, approx float64) float64 {
+	corr := func(lag int) float64 {
+		c := 0.0
+		for i := lag; i < len(x); i++ {
+			c += x[i] * x[i-lag]
+		}
+		return c
+	}
+	period := int(sampleRate / approx)
+	best := 0
+	for lag := period * 9 / 10; lag <= period*11/10; lag++ {
+		if best == 0 || corr(lag) > corr(best) {
+			best = lag
+		}
+	}
+	l, c, r := corr(best-1), corr(best), corr(best+1)
+	return sampleRate / (float64(best) + (l-r)/(2*(l-2*c+r)))
+}
+
+func rms(x []float64) float64 {
+	s := 0.0
+	for _, x := range x {
+		s += x * x
+	}
+	return math.Sqrt(s / float64(len(x)))
+}
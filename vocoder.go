@@ -0,0 +1,231 @@
+package audio
+
+import (
+	"math"
+	"math/cmplx"
+
+	"github.com/ktye/fft"
+)
+
+// PhaseVocoder shifts the pitch of a stream without changing its duration.  It uses a phase vocoder with
+// identity phase locking (Laroche and Dolson), which keeps the bins around each spectral peak coherent and so
+// reduces the "phasiness" of the basic phase vocoder.  Output is delayed by Latency samples.
+//
+// For time stretching of buffers, see PhaseVocode.
+type PhaseVocoder struct {
+	pv      phaseVocoder
+	ratio   float64
+	in, out []float64
+	i       int
+}
+
+// NewPhaseVocoder returns a PhaseVocoder with the given FFT size, a power of 2.  Larger sizes resolve
+// low frequencies better but smear transients more; 2048 is a good choice at 48 kHz.
+func NewPhaseVocoder(size int) *PhaseVocoder {
+	return &PhaseVocoder{
+		pv:    newPhaseVocoder(size),
+		ratio: 1,
+		in:    make([]float64, size),
+		out:   make([]float64, size),
+	}
+}
+
+// Pitch sets the ratio by which frequencies are multiplied.
+func (v *PhaseVocoder) Pitch(ratio float64) *PhaseVocoder {
+	v.ratio = ratio
+	return v
+}
+
+// Latency returns the delay of the output in samples.
+func (v *PhaseVocoder) Latency() int {
+	return len(v.in)
+}
+
+func (v *PhaseVocoder) Filter(x float64) float64 {
+	n := len(v.in)
+	hop := n / 4
+	v.in[n-hop+v.i] = x
+	y := v.out[v.i]
+	v.i++
+	if v.i == hop {
+		v.i = 0
+		frame := v.pv.frame(v.in, hop, hop, v.ratio)
+		copy(v.out, v.out[hop:])
+		for i := n - hop; i < n; i++ {
+			v.out[i] = 0
+		}
+		for i, f := range frame {
+			v.out[i] += f
+		}
+		copy(v.in, v.in[hop:])
+	}
+	return y
+}
+
+// PhaseVocode time-stretches x by stretch (2 is twice as long) and multiplies its frequencies by pitch, using
+// a phase vocoder with identity phase locking and the given FFT size (a power of 2, or 0 for 2048).
+func PhaseVocode(x []float64, stretch, pitch float64, size int) []float64 {
+	if size == 0 {
+		size = 2048
+	}
+	pv := newPhaseVocoder(size)
+	hs := size / 4
+	n := int(float64(len(x)) * stretch)
+	out := make([]float64, n+2*size)
+	in := make([]float64, size)
+	prev := 0
+	for j := 0; ; j++ {
+		// Frames are centered at j*hs in the output and j*hs/stretch in the input.
+		start := int(math.Floor(float64(j*hs)/stretch+.5)) - size/2
+		if start >= len(x) {
+			break
+		}
+		for i := range in {
+			in[i] = 0
+			if k := start + i; k >= 0 && k < len(x) {
+				in[i] = x[k]
+			}
+		}
+		ha := start - prev
+		if j == 0 {
+			ha = -1
+		}
+		prev = start
+		for i, y := range pv.frame(in, ha, hs, pitch) {
+			if k := j*hs + i; k < len(out) {
+				out[k] += y
+			}
+		}
+	}
+	return out[size/2 : size/2+n]
+}
+
+type phaseVocoder struct {
+	fft                fft.FFT
+	window             []float64
+	buf, spec          []complex128
+	phase, prevPhase   []float64 // analysis phases
+	synthPhase, synth2 []float64
+	mag, freq          []float64 // magnitudes, instantaneous frequencies (radians per sample)
+	peaks              []int
+	out                []float64
+	started            bool
+}
+
+func newPhaseVocoder(size int) phaseVocoder {
+	f, err := fft.New(size)
+	if err != nil {
+		panic(err)
+	}
+	if f.N != size {
+		panic("phase vocoder size must be a power of 2")
+	}
+	window := make([]float64, size)
+	for i := range window {
+		window[i] = (1 - math.Cos(2*math.Pi*float64(i)/float64(size))) / 2
+	}
+	bins := size/2 + 1
+	return phaseVocoder{
+		fft:        f,
+		window:     window,
+		buf:        make([]complex128, size),
+		spec:       make([]complex128, size),
+		phase:      make([]float64, bins),
+		prevPhase:  make([]float64, bins),
+		synthPhase: make([]float64, bins),
+		synth2:     make([]float64, bins),
+		mag:        make([]float64, bins),
+		freq:       make([]float64, bins),
+		out:        make([]float64, size),
+	}
+}
+
+// frame processes a frame of input that follows the previous one by ha samples (or -1 for the first frame),
+// returning a windowed frame of output to be overlap-added hs samples after the previous one.
+func (pv *phaseVocoder) frame(in []float64, ha, hs int, ratio float64) []float64 {
+	n := len(in)
+	// Rotate frames by half their length so that phases are relative to their centers.  Then the bins around
+	// a peak have similar phases and can be interpolated.
+	for i, x := range in {
+		pv.buf[(i+n/2)%n] = complex(x*pv.window[i], 0)
+	}
+	copy(pv.spec, pv.fft.Transform(pv.buf))
+	for k := range pv.mag {
+		pv.mag[k], pv.phase[k] = cmplx.Polar(pv.spec[k])
+		w := 2 * math.Pi * float64(k) / float64(n)
+		if ha > 0 {
+			dp := math.Remainder(pv.phase[k]-pv.prevPhase[k]-w*float64(ha), 2*math.Pi)
+			pv.freq[k] = w + dp/float64(ha)
+		} else if ha < 0 {
+			pv.freq[k] = w
+		}
+		pv.prevPhase[k] = pv.phase[k]
+	}
+
+	pv.peaks = pv.peaks[:0]
+	for k := 1; k < len(pv.mag)-1; k++ {
+		if pv.mag[k] > pv.mag[k-1] && pv.mag[k] >= pv.mag[k+1] {
+			pv.peaks = append(pv.peaks, k)
+		}
+	}
+
+	spectrum := pv.buf
+	for k := range spectrum {
+		spectrum[k] = 0
+	}
+	for k := range pv.synth2 {
+		pv.synth2[k] = pv.synthPhase[k] + float64(hs)*2*math.Pi*float64(k)/float64(n)
+	}
+	for i, p := range pv.peaks {
+		// Each peak's region of influence extends halfway to its neighbours.
+		lo, hi := 0, len(pv.mag)
+		if i > 0 {
+			lo = (pv.peaks[i-1] + p + 1) / 2
+		}
+		if i < len(pv.peaks)-1 {
+			hi = (p + pv.peaks[i+1] + 1) / 2
+		}
+		// Shift the region so that the peak moves to its new frequency.
+		shift := (ratio - 1) * pv.freq[p] * float64(n) / (2 * math.Pi)
+		p2 := int(math.Floor(float64(p) + shift + .5))
+		if p2 >= len(pv.mag) {
+			break
+		}
+		if p2 < 0 {
+			continue
+		}
+		theta := pv.phase[p]
+		if pv.started {
+			theta = pv.synthPhase[p2] + float64(hs)*ratio*pv.freq[p]
+		}
+		rot := cmplx.Rect(1, theta-pv.phase[p])
+		for k2 := int(math.Ceil(float64(lo) + shift)); k2 < int(math.Ceil(float64(hi)+shift)); k2++ {
+			if k2 < 0 || k2 >= len(pv.mag) {
+				continue
+			}
+			j, f := math.Modf(float64(k2) - shift)
+			x := complex(1-f, 0) * pv.spec[int(j)]
+			if f > 0 && int(j)+1 < len(pv.mag) {
+				x += complex(f, 0) * pv.spec[int(j)+1]
+			}
+			y := x * rot
+			spectrum[k2] += y
+			pv.synth2[k2] = cmplx.Phase(y)
+		}
+	}
+	pv.synthPhase, pv.synth2 = pv.synth2, pv.synthPhase
+	pv.started = true
+
+	spectrum[0] = complex(real(spectrum[0]), 0)
+	spectrum[n/2] = complex(real(spectrum[n/2]), 0)
+	for k := 1; k < n/2; k++ {
+		spectrum[n-k] = cmplx.Conj(spectrum[k])
+	}
+	y := pv.fft.Inverse(spectrum)
+	// Normalize for the overlap of squared Hann windows.
+	norm := 8 * float64(hs) / (3 * float64(n))
+	for i := range pv.out {
+		pv.out[i] = real(y[(i+n/2)%n]) * pv.window[i] * norm
+	}
+	return pv.out
+}
@@ -0,0 +1,57 @@
+package audio
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPhaseVocode(t *testing.T) {
+	const sampleRate = 48000
+	x := make([]float64, sampleRate)
+	for i := range x {
+		x[i] = math.Sin(2 * math.Pi * 440 * float64(i) / sampleRate)
+	}
+	for _, c := range []struct{ stretch, pitch float64 }{{2, 1}, {.5, 1}, {1, 1.5}, {1.5, .75}} {
+		y := PhaseVocode(x, c.stretch, c.pitch, 0)
+		if len(y) != int(sampleRate*c.stretch) {
+			t.Errorf("stretch %v: expected length %d, got %d", c.stretch, int(sampleRate*c.stretch), len(y))
+		}
+		mid := y[len(y)/4 : 3*len(y)/4]
+		if f := estimateFreq(mid, sampleRate, 440*c.pitch); math.Abs(f/(440*c.pitch)-1) > .002 {
+			t.Errorf("stretch %v, pitch %v: expected frequency %f, got %f", c.stretch, c.pitch, 440*c.pitch, f)
+		}
+		if a := rms(mid) * math.Sqrt2; math.Abs(a-1) > .1 {
+			t.Errorf("stretch %v, pitch %v: expected amplitude 1, got %f", c.stretch, c.pitch, a)
+		}
+	}
+}
+
+func TestPhaseVocoder(t *testing.T) {
+	const sampleRate = 48000
+	x := make([]float64, sampleRate)
+	for i := range x {
+		x[i] = math.Sin(2*math.Pi*1000*float64(i)/sampleRate) + .5*math.Sin(2*math.Pi*3100*float64(i)/sampleRate)
+	}
+
+	// At unit pitch, the output is the input delayed by the latency.
+	v := NewPhaseVocoder(1024)
+	maxErr := 0.0
+	for i, x_ := range x {
+		y := v.Filter(x_)
+		if j := i - v.Latency(); j >= 2048 {
+			maxErr = math.Max(maxErr, math.Abs(y-x[j]))
+		}
+	}
+	if maxErr > .01 {
+		t.Errorf("expected delayed input, got max error %f", maxErr)
+	}
+
+	v = NewPhaseVocoder(2048).Pitch(1.25)
+	y := make([]float64, len(x))
+	for i := range x {
+		y[i] = v.Filter(math.Sin(2 * math.Pi * 1000 * float64(i) / sampleRate))
+	}
+	if f := estimateFreq(y[sampleRate/4:], sampleRate, 1250); math.Abs(f/1250-1) > .002 {
+		t.Errorf("expected frequency 1250, got %f", f)
+	}
+}
@@ -0,0 +1,121 @@
+package audio
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+)
+
+// ReadWAV reads a WAV file containing 8-, 16-, 24- or 32-bit integer PCM or 32- or 64-bit float samples.
+// It returns the samples of each channel, scaled to the range -1..1, and the sample rate.
+func ReadWAV(r io.Reader) (channels [][]float64, sampleRate float64, err error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, 0, err
+	}
+	chunks, err := parseRIFF(b, "WAVE")
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var (
+		format, numChannels, bits int
+		data                      []byte
+		haveFormat                bool
+	)
+	for _, c := range chunks {
+		switch c.id {
+		case "fmt ":
+			if len(c.data) < 16 {
+				return nil, 0, errors.New("wav: short fmt chunk")
+			}
+			format = int(binary.LittleEndian.Uint16(c.data[0:]))
+			numChannels = int(binary.LittleEndian.Uint16(c.data[2:]))
+			sampleRate = float64(binary.LittleEndian.Uint32(c.data[4:]))
+			bits = int(binary.LittleEndian.Uint16(c.data[14:]))
+			if format == 0xFFFE && len(c.data) >= 26 {
+				// WAVE_FORMAT_EXTENSIBLE:  the format is the first two bytes of the subformat GUID.
+				format = int(binary.LittleEndian.Uint16(c.data[24:]))
+			}
+			haveFormat = true
+		case "data":
+			data = c.data
+		}
+	}
+	if !haveFormat {
+		return nil, 0, errors.New("wav: missing fmt chunk")
+	}
+	if numChannels == 0 {
+		return nil, 0, errors.New("wav: no channels")
+	}
+
+	var sample func([]byte) float64
+	switch {
+	case format == 1 && bits == 8:
+		sample = func(b []byte) float64 { return (float64(b[0]) - 128) / 128 }
+	case format == 1 && bits == 16:
+		sample = func(b []byte) float64 { return float64(int16(binary.LittleEndian.Uint16(b))) / (1 << 15) }
+	case format == 1 && bits == 24:
+		sample = func(b []byte) float64 {
+			return float64(int32(uint32(b[0])<<8|uint32(b[1])<<16|uint32(b[2])<<24)>>8) / (1 << 23)
+		}
+	case format == 1 && bits == 32:
+		sample = func(b []byte) float64 { return float64(int32(binary.LittleEndian.Uint32(b))) / (1 << 31) }
+	case format == 3 && bits == 32:
+		sample = func(b []byte) float64 { return float64(math.Float32frombits(binary.LittleEndian.Uint32(b))) }
+	case format == 3 && bits == 64:
+		sample = func(b []byte) float64 { return math.Float64frombits(binary.LittleEndian.Uint64(b)) }
+	default:
+		return nil, 0, fmt.Errorf("wav: unsupported format %d with %d bits per sample", format, bits)
+	}
+
+	size := bits / 8
+	n := len(data) / (size * numChannels)
+	channels = make([][]float64, numChannels)
+	for c := range channels {
+		channels[c] = make([]float64, n)
+		for i := range channels[c] {
+			channels[c][i] = sample(data[(i*numChannels+c)*size:])
+		}
+	}
+	return channels, sampleRate, nil
+}
+
+type riffChunk struct {
+	id   string
+	data []byte
+}
+
+// parseRIFF checks that b is a RIFF file of the given form type and returns its chunks.
+func parseRIFF(b []byte, form string) ([]riffChunk, error) {
+	if len(b) < 12 || string(b[0:4]) != "RIFF" || string(b[8:12]) != form {
+		return nil, fmt.Errorf("not a RIFF %s file", form)
+	}
+	size := int(binary.LittleEndian.Uint32(b[4:]))
+	if size+8 < len(b) {
+		b = b[:size+8]
+	}
+	return parseChunks(b[12:])
+}
+
+// parseChunks parses a sequence of RIFF chunks, such as the contents of a RIFF or LIST chunk.
+func parseChunks(b []byte) ([]riffChunk, error) {
+	var chunks []riffChunk
+	for len(b) >= 8 {
+		id := string(b[0:4])
+		size := int(binary.LittleEndian.Uint32(b[4:]))
+		b = b[8:]
+		if size > len(b) {
+			return nil, fmt.Errorf("RIFF chunk %q is truncated", id)
+		}
+		chunks = append(chunks, riffChunk{id, b[:size]})
+		if size%2 == 1 && size < len(b) {
+			size++ // chunks are padded to even length
+		}
+		b = b[size:]
+	}
+	return chunks, nil
+}
@@ -0,0 +1,116 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+)
+
+func TestReadWAV(t *testing.T) {
+	channels := [][]float64{make([]float64, 1000), make([]float64, 1000)}
+	for i := range channels[0] {
+		channels[0][i] = .9 * math.Sin(2*math.Pi*float64(i)/100)
+		channels[1][i] = .5 * math.Cos(2*math.Pi*float64(i)/37)
+	}
+	for _, c := range []struct {
+		format, bits int
+		extensible   bool
+	}{
+		{1, 8, false},
+		{1, 16, false},
+		{1, 24, false},
+		{1, 32, false},
+		{3, 32, false},
+		{3, 64, false},
+		{1, 24, true},
+		{3, 32, true},
+	} {
+		x, sampleRate, err := ReadWAV(bytes.NewReader(encodeWAV(channels, 44100, c.format, c.bits, c.extensible)))
+		if err != nil {
+			t.Errorf("format %d, %d bits, extensible %v: %v", c.format, c.bits, c.extensible, err)
+			continue
+		}
+		if sampleRate != 44100 || len(x) != 2 || len(x[0]) != 1000 || len(x[1]) != 1000 {
+			t.Errorf("format %d, %d bits, extensible %v: got %d channels of %d samples at %v Hz", c.format, c.bits, c.extensible, len(x), len(x[0]), sampleRate)
+			continue
+		}
+		tolerance := 1e-12
+		if c.format == 1 {
+			tolerance = 1 / math.Exp2(float64(c.bits-1))
+		} else if c.bits == 32 {
+			tolerance = 1e-7
+		}
+		for ch := range x {
+			for i := range x[ch] {
+				if d := math.Abs(x[ch][i] - channels[ch][i]); d > tolerance {
+					t.Fatalf("format %d, %d bits, extensible %v: channel %d sample %d is %v, want %v", c.format, c.bits, c.extensible, ch, i, x[ch][i], channels[ch][i])
+				}
+			}
+		}
+	}
+
+	if _, _, err := ReadWAV(bytes.NewReader(encodeWAV(channels, 44100, 1, 12, false))); err == nil {
+		t.Error("expected an error for 12-bit samples")
+	}
+	if _, _, err := ReadWAV(bytes.NewReader([]byte("RIFF\x04\x00\x00\x00AVI "))); err == nil {
+		t.Error("expected an error for a non-WAV file")
+	}
+}
+
+// encodeWAV encodes interleaved channels in the given format (1 for integer PCM, 3 for float) and number
+// of bits per sample, optionally using WAVE_FORMAT_EXTENSIBLE.  Samples must be within -1..1 (exclusive, for
+// integer formats).
+func encodeWAV(channels [][]float64, sampleRate, format, bits int, extensible bool) []byte {
+	var data bytes.Buffer
+	w := func(b *bytes.Buffer, v interface{}) { binary.Write(b, binary.LittleEndian, v) }
+	for i := range channels[0] {
+		for _, ch := range channels {
+			x := ch[i]
+			switch {
+			case format == 3 && bits == 32:
+				w(&data, float32(x))
+			case format == 3 && bits == 64:
+				w(&data, x)
+			case bits == 8:
+				data.WriteByte(byte(math.Floor(x*128+.5) + 128))
+			default:
+				v := int32(math.Floor(x*math.Exp2(float64(bits-1)) + .5))
+				for b := 0; b < bits/8; b++ {
+					data.WriteByte(byte(v >> uint(8*b)))
+				}
+			}
+		}
+	}
+
+	var fmtChunk bytes.Buffer
+	formatTag := format
+	if extensible {
+		formatTag = 0xFFFE
+	}
+	blockAlign := len(channels) * bits / 8
+	w(&fmtChunk, uint16(formatTag))
+	w(&fmtChunk, uint16(len(channels)))
+	w(&fmtChunk, uint32(sampleRate))
+	w(&fmtChunk, uint32(sampleRate*blockAlign))
+	w(&fmtChunk, uint16(blockAlign))
+	w(&fmtChunk, uint16(bits))
+	if extensible {
+		w(&fmtChunk, uint16(22))
+		w(&fmtChunk, uint16(bits))
+		w(&fmtChunk, uint32(3))
+		w(&fmtChunk, uint16(format))
+		fmtChunk.WriteString("\x00\x00\x00\x00\x10\x00\x80\x00\x00\xaa\x00\x38\x9b\x71")
+	}
+
+	var b bytes.Buffer
+	b.WriteString("RIFF")
+	w(&b, uint32(4+8+fmtChunk.Len()+8+data.Len()))
+	b.WriteString("WAVEfmt ")
+	w(&b, uint32(fmtChunk.Len()))
+	b.Write(fmtChunk.Bytes())
+	b.WriteString("data")
+	w(&b, uint32(data.Len()))
+	b.Write(data.Bytes())
+	return b.Bytes()
+}
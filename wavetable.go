@@ -0,0 +1,203 @@
+package audio
+
+import (
+	"io"
+	"math"
+
+	"github.com/ktye/fft"
+)
+
+const (
+	wavetableSize   = 2048
+	wavetableLevels = 11 // level i holds harmonics 1..(wavetableSize/2)>>i
+)
+
+// A Wavetable is a single-cycle waveform stored as a set of band-limited mip levels, one per octave, so that
+// it can be played at any frequency without aliasing.
+type Wavetable struct {
+	levels [wavetableLevels][]float64
+}
+
+// NewWavetable returns a Wavetable whose i'th sine harmonic has amplitude harmonics[i-1].
+func NewWavetable(harmonics []float64) *Wavetable {
+	spectrum := make([]complex128, wavetableSize)
+	for i, a := range harmonics {
+		h := i + 1
+		if h >= wavetableSize/2 {
+			break
+		}
+		spectrum[h] = complex(0, -a*wavetableSize/2)
+		spectrum[wavetableSize-h] = complex(0, a*wavetableSize/2)
+	}
+	return newWavetableFromSpectrum(spectrum)
+}
+
+// NewWavetableFunc returns a Wavetable with a cycle of f, which is sampled for phases in 0..1.
+func NewWavetableFunc(f func(phase float64) float64) *Wavetable {
+	x := make([]float64, wavetableSize)
+	for i := range x {
+		x[i] = f(float64(i) / wavetableSize)
+	}
+	return NewWavetableCycle(x)
+}
+
+// NewWavetableCycle returns a Wavetable with the single cycle x, which may have any length.  Harmonics that
+// don't fit in the table are dropped.
+func NewWavetableCycle(x []float64) *Wavetable {
+	spectrum := make([]complex128, wavetableSize)
+	n := len(x)
+	if n == 0 {
+		return newWavetableFromSpectrum(spectrum)
+	}
+	// The DFT of x at its own length, so that its harmonics are copied exactly rather than resampled.
+	cos, sin := make([]float64, n), make([]float64, n)
+	for i := range cos {
+		sin[i], cos[i] = math.Sincos(2 * math.Pi * float64(i) / float64(n))
+	}
+	scale := float64(wavetableSize) / float64(n)
+	for h := 0; h <= n/2 && h < wavetableSize/2; h++ {
+		var re, im float64
+		for i, x := range x {
+			j := h * i % n
+			re += x * cos[j]
+			im -= x * sin[j]
+		}
+		c := complex(re*scale, im*scale)
+		if h == 0 {
+			spectrum[0] = c
+			continue
+		}
+		if 2*h == n {
+			c /= 2 // the Nyquist bin of x stands for both positive and negative frequencies
+		}
+		spectrum[h] = c
+		spectrum[wavetableSize-h] = complex(real(c), -imag(c))
+	}
+	return newWavetableFromSpectrum(spectrum)
+}
+
+// ReadWavetables reads a WAV file containing a sequence of single cycles of cycleLength samples each, as used
+// by many wavetable synthesizers.  If cycleLength is zero, the whole file is a single cycle.  Only the first
+// channel is used.
+func ReadWavetables(r io.Reader, cycleLength int) ([]*Wavetable, error) {
+	channels, _, err := ReadWAV(r)
+	if err != nil {
+		return nil, err
+	}
+	x := channels[0]
+	if cycleLength <= 0 {
+		cycleLength = len(x)
+	}
+	var tables []*Wavetable
+	for ; len(x) >= cycleLength && cycleLength > 0; x = x[cycleLength:] {
+		tables = append(tables, NewWavetableCycle(x[:cycleLength]))
+	}
+	return tables, nil
+}
+
+func wavetableFFT() fft.FFT {
+	f, err := fft.New(wavetableSize)
+	if err != nil {
+		panic(err)
+	}
+	return f
+}
+
+func newWavetableFromSpectrum(spectrum []complex128) *Wavetable {
+	t := &Wavetable{}
+	f := wavetableFFT()
+	buf := make([]complex128, wavetableSize)
+	for l := range t.levels {
+		maxHarmonic := (wavetableSize / 2) >> uint(l)
+		for i := range buf {
+			buf[i] = 0
+		}
+		buf[0] = spectrum[0]
+		for h := 1; h <= maxHarmonic && h < wavetableSize/2; h++ {
+			buf[h] = spectrum[h]
+			buf[wavetableSize-h] = spectrum[wavetableSize-h]
+		}
+		f.Inverse(buf)
+		level := make([]float64, wavetableSize)
+		for i := range level {
+			level[i] = real(buf[i])
+		}
+		t.levels[l] = level
+	}
+	return t
+}
+
+// read returns the value at phase (0..1) of the given mip level, crossfading between adjacent levels.
+func (t *Wavetable) read(level, phase float64) float64 {
+	l, f := math.Modf(level)
+	i := int(l)
+	if i >= wavetableLevels-1 {
+		return t.readLevel(wavetableLevels-1, phase)
+	}
+	x := t.readLevel(i, phase)
+	if f == 0 {
+		return x
+	}
+	return Crossfade(x, f, t.readLevel(i+1, phase))
+}
+
+func (t *Wavetable) readLevel(level int, phase float64) float64 {
+	x := t.levels[level]
+	j, f := math.Modf(phase * wavetableSize)
+	i := int(j)
+	const mask = wavetableSize - 1
+	return Interp3(f, x[(i-1)&mask], x[i&mask], x[(i+1)&mask], x[(i+2)&mask])
+}
+
+// WavetableOsc plays a sequence of Wavetables, morphing between adjacent tables according to its position.
+type WavetableOsc struct {
+	tables []*Wavetable
+	srate  float64
+	freq   float64
+	p, dp  float64
+	level  float64
+	pos    float64
+}
+
+func NewWavetableOsc(tables ...*Wavetable) *WavetableOsc {
+	if len(tables) == 0 {
+		panic("NewWavetableOsc: no tables")
+	}
+	return &WavetableOsc{tables: tables}
+}
+
+func (o *WavetableOsc) InitAudio(p Params) {
+	o.srate = p.SampleRate
+	o.Freq(o.freq)
+}
+
+func (o *WavetableOsc) Freq(freq float64) *WavetableOsc {
+	o.freq = freq
+	o.dp = freq / o.srate
+	// Choose the mip level whose highest harmonic is safely below the Nyquist frequency.
+	o.level = math.Max(0, math.Log2(2*wavetableSize*math.Abs(o.dp)))
+	return o
+}
+
+func (o *WavetableOsc) Phase(x float64) *WavetableOsc {
+	o.p = x - math.Floor(x)
+	return o
+}
+
+// Position sets the morph position, from 0 (the first table) to len(tables)-1 (the last table).
+func (o *WavetableOsc) Position(x float64) *WavetableOsc {
+	o.pos = math.Max(0, math.Min(float64(len(o.tables)-1), x))
+	return o
+}
+
+func (o *WavetableOsc) Sing() float64 {
+	o.p += o.dp
+	o.p -= math.Floor(o.p)
+	i, f := math.Modf(o.pos)
+	t := int(i)
+	x := o.tables[t].read(o.level, o.p)
+	if f == 0 {
+		return x
+	}
+	return Crossfade(x, f, o.tables[t+1].read(o.level, o.p))
+}
@@ -0,0 +1,149 @@
+package audio
+
+import (
+	"bytes"
+	"math"
+	"testing"
+)
+
+// wavetableError plays osc at freq and returns the largest difference from f(phase).
+func wavetableError(osc *WavetableOsc, freq float64, f func(phase float64) float64) float64 {
+	const sampleRate = 48000
+	Init(osc, Params{sampleRate})
+	osc.Freq(freq)
+	maxErr := 0.0
+	for n := 1; n <= sampleRate/10; n++ {
+		p := freq * float64(n) / sampleRate
+		maxErr = math.Max(maxErr, math.Abs(osc.Sing()-f(p-math.Floor(p))))
+	}
+	return maxErr
+}
+
+func sine(phase float64) float64 { return math.Sin(2 * math.Pi * phase) }
+
+func TestWavetableOsc(t *testing.T) {
+	if e := wavetableError(NewWavetableOsc(NewWavetable([]float64{1})), 440, sine); e > 1e-6 {
+		t.Errorf("sine:  error %g", e)
+	}
+	harmonics := func(phase float64) float64 { return .5*sine(phase) + .25*sine(3*phase) }
+	if e := wavetableError(NewWavetableOsc(NewWavetable([]float64{.5, 0, .25})), 100, harmonics); e > 1e-6 {
+		t.Errorf("harmonics:  error %g", e)
+	}
+
+	// Morphing crossfades between adjacent tables.
+	osc := NewWavetableOsc(NewWavetable([]float64{1}), NewWavetable([]float64{0, 1}), NewWavetable([]float64{0, 0, 1}))
+	osc.Position(1.25)
+	morph := func(phase float64) float64 { return .75*sine(2*phase) + .25*sine(3*phase) }
+	if e := wavetableError(osc, 100, morph); e > 1e-6 {
+		t.Errorf("morph:  error %g", e)
+	}
+}
+
+func TestWavetableLevels(t *testing.T) {
+	const sampleRate = 48000
+	osc := NewWavetableOsc(NewWavetable([]float64{1}))
+	Init(osc, Params{sampleRate})
+	for _, freq := range []float64{20, 100, 440, 1000, 3000, 9000} {
+		osc.Freq(freq)
+		// The lower of the two levels being crossfaded has the most harmonics.
+		l := int(osc.level)
+		highest := float64(int(wavetableSize/2)>>uint(l)) * freq
+		if highest >= sampleRate/2 {
+			t.Errorf("%v Hz:  level %v has harmonics up to %v Hz, above the Nyquist frequency", freq, osc.level, highest)
+		}
+		if osc.level > 0 && highest < sampleRate/4 {
+			t.Errorf("%v Hz:  level %v has harmonics only up to %v Hz", freq, osc.level, highest)
+		}
+	}
+
+	saw := make([]float64, wavetableSize/2)
+	for i := range saw {
+		saw[i] = 1 / float64(i+1)
+	}
+	naive, wt := aliasingEnergy(new(SawOsc)), aliasingEnergy(NewWavetableOsc(NewWavetable(saw)))
+	t.Logf("aliasing:  SawOsc %.1f dB, WavetableOsc %.1f dB", naive, wt)
+	if wt > -60 {
+		t.Errorf("expected aliasing below -60 dB, got %.1f dB", wt)
+	}
+}
+
+func TestNewWavetableCycle(t *testing.T) {
+	// A cycle of any length is resampled to the table size.
+	for _, n := range []int{100, 600, 5000} {
+		x := make([]float64, n)
+		for i := range x {
+			x[i] = sine(float64(i) / float64(n))
+		}
+		if e := wavetableError(NewWavetableOsc(NewWavetableCycle(x)), 100, sine); e > 1e-4 {
+			t.Errorf("cycle of %d samples:  error %g", n, e)
+		}
+	}
+}
+
+func TestNewWavetableCycleHarmonics(t *testing.T) {
+	// Harmonics above those the table can hold are dropped rather than folded back, and those below are
+	// kept exactly, without interpolation images.
+	for _, c := range []struct {
+		n        int
+		harmonic []int
+		expected func(phase float64) float64
+	}{
+		{4096, []int{1, 1500}, sine},
+		{64, []int{1, 20}, func(phase float64) float64 { return sine(phase) + sine(20*phase) }},
+		{64, []int{3, 32}, func(phase float64) float64 { return sine(3*phase) + math.Cos(64*math.Pi*phase) }},
+	} {
+		x := make([]float64, c.n)
+		for i := range x {
+			p := float64(i) / float64(c.n)
+			for _, h := range c.harmonic {
+				if 2*h == c.n {
+					x[i] += math.Cos(2 * math.Pi * float64(h) * p)
+				} else {
+					x[i] += sine(float64(h) * p)
+				}
+			}
+		}
+		table := NewWavetableCycle(x)
+		maxErr := 0.0
+		for i, y := range table.levels[0] {
+			maxErr = math.Max(maxErr, math.Abs(y-c.expected(float64(i)/wavetableSize)))
+		}
+		if maxErr > 1e-9 {
+			t.Errorf("cycle of %d samples with harmonics %v:  error %g", c.n, c.harmonic, maxErr)
+		}
+	}
+}
+
+func TestReadWavetables(t *testing.T) {
+	const cycle = 256
+	var x []float64
+	for h := 1; h <= 3; h++ {
+		for i := 0; i < cycle; i++ {
+			x = append(x, .5*sine(float64(h*i)/cycle))
+		}
+	}
+	wav := encodeWAV([][]float64{x}, 44100, 3, 32, false)
+
+	tables, err := ReadWavetables(bytes.NewReader(wav), cycle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tables) != 3 {
+		t.Fatalf("expected 3 tables, got %d", len(tables))
+	}
+	for i, table := range tables {
+		h := float64(i + 1)
+		f := func(phase float64) float64 { return .5 * sine(h*phase) }
+		if e := wavetableError(NewWavetableOsc(table), 100, f); e > 1e-4 {
+			t.Errorf("table %d:  error %g", i, e)
+		}
+	}
+
+	tables, err = ReadWavetables(bytes.NewReader(wav), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tables) != 1 {
+		t.Errorf("expected the whole file as 1 table, got %d", len(tables))
+	}
+}